@@ -1,10 +1,13 @@
 package cli
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/x-motemen/gore"
 )
@@ -46,7 +49,13 @@ func (c *cli) parseArgs(args []string) (*gore.Gore, error) {
 Version: %s (rev: %s/%s)
 
 Synopsis:
-    %% gore
+    %% gore [options]
+    %% gore [options] <script file>
+    %% gore [options] -e <code>
+    %% <command> | gore [options]
+    %% gore [options] test <transcript file>
+    %% gore [options] kernel <connection file>
+    %% gore [options] serve [<socket file>]
 
 Options:
 `, gore.Version, revision, runtime.Version())
@@ -56,12 +65,38 @@ Options:
 	var autoImport bool
 	fs.BoolVar(&autoImport, "autoimport", false, "formats and adjusts imports automatically")
 
+	var printer string
+	fs.StringVar(&printer, "printer", "", "the printer of the values (pp, %#v, %+v, %v, json, spew or a function)")
+	var showType bool
+	fs.BoolVar(&showType, "showtype", false, "shows the types of the values printed")
+
+	var persist bool
+	fs.BoolVar(&persist, "persist", false, "restore variables saved by the previous run instead of evaluating the previous inputs again")
+
 	var extFiles string
 	fs.StringVar(&extFiles, "context", "", "import packages, functions, variables and constants from external golang source files")
 
 	var packageName string
 	fs.StringVar(&packageName, "pkg", "", "the package where the session will be run inside")
 
+	var loadFile string
+	fs.StringVar(&loadFile, "load", "", "replay the inputs saved by :save command")
+
+	var code string
+	fs.StringVar(&code, "e", "", "evaluate the code and exit")
+
+	var timeout time.Duration
+	fs.DurationVar(&timeout, "timeout", 0, "kill the program running longer than the duration")
+
+	var wait time.Duration
+	fs.DurationVar(&wait, "wait", 0, "wait for the goroutines at most for the duration, and report the ones still running")
+
+	var memoryLimit string
+	fs.StringVar(&memoryLimit, "memlimit", "", "limit the memory of the program (e.g. 512M, Linux only)")
+
+	var cpuLimit time.Duration
+	fs.DurationVar(&cpuLimit, "cpulimit", 0, "limit the CPU time of the program (Linux only)")
+
 	var showVersion bool
 	fs.BoolVar(&showVersion, "version", false, "print gore version")
 
@@ -75,11 +110,63 @@ Options:
 		return nil, flag.ErrHelp
 	}
 
-	return gore.New(
+	var scriptFile, testFile, kernelFile, serveAddr string
+	var serve bool
+	args = fs.Args()
+	if len(args) > 0 {
+		switch args[0] {
+		case "test":
+			testFile, args, err = c.subcommandArg(args, "transcript file")
+		case "kernel":
+			kernelFile, args, err = c.subcommandArg(args, "connection file")
+		case "serve":
+			serve, args = true, args[1:]
+			if len(args) > 0 {
+				serveAddr, args = args[0], args[1:]
+			}
+		default:
+			scriptFile, args = args[0], args[1:]
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(args) > 0 {
+		fmt.Fprintf(c.errWriter, "too many arguments: %s\n", strings.Join(fs.Args(), " "))
+		return nil, errors.New("too many arguments")
+	}
+
+	opts := []gore.Option{
 		gore.AutoImport(autoImport),
+		gore.Printer(printer),
+		gore.ShowType(showType),
+		gore.Persist(persist),
 		gore.ExtFiles(extFiles),
 		gore.PackageName(packageName),
+		gore.LoadFile(loadFile),
+		gore.Code(code),
+		gore.ScriptFile(scriptFile),
+		gore.TestFile(testFile),
+		gore.KernelFile(kernelFile),
+		gore.Timeout(timeout),
+		gore.Wait(wait),
+		gore.MemoryLimit(memoryLimit),
+		gore.CPULimit(cpuLimit),
 		gore.OutWriter(c.outWriter),
 		gore.ErrWriter(c.errWriter),
-	), nil
+	}
+	if serve {
+		opts = append(opts, gore.Serve(serveAddr))
+	}
+
+	return gore.New(opts...), nil
+}
+
+// subcommandArg returns the file argument of the subcommand.
+func (c *cli) subcommandArg(args []string, name string) (string, []string, error) {
+	if len(args) == 1 {
+		fmt.Fprintf(c.errWriter, "%s: %s is required\n", args[0], name)
+		return "", nil, fmt.Errorf("%s is required", name)
+	}
+	return args[1], args[2:], nil
 }
@@ -40,3 +40,23 @@ func TestCliRun_Unknown(t *testing.T) {
 	assert.Contains(t, stdout.String(), "gore -")
 	assert.Contains(t, stderr.String(), "flag provided but not defined: -foobar")
 }
+
+func TestCliRun_TooManyArguments(t *testing.T) {
+	var stdout, stderr strings.Builder
+	c := &cli{&stdout, &stderr}
+	code := c.run([]string{"foo.gore", "bar.gore"})
+	require.Equal(t, exitCodeErr, code)
+
+	assert.Equal(t, "", stdout.String())
+	assert.Equal(t, "too many arguments: foo.gore bar.gore\n", stderr.String())
+}
+
+func TestCliRun_TestWithoutFile(t *testing.T) {
+	var stdout, stderr strings.Builder
+	c := &cli{&stdout, &stderr}
+	code := c.run([]string{"test"})
+	require.Equal(t, exitCodeErr, code)
+
+	assert.Equal(t, "", stdout.String())
+	assert.Equal(t, "test: transcript file is required\n", stderr.String())
+}
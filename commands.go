@@ -10,6 +10,7 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -45,11 +46,50 @@ func init() {
 			complete: completeDoc,
 			document: "print the type of expression",
 		},
+		{
+			name:     commandName("vars"),
+			action:   actionVars,
+			arg:      "[-v]",
+			document: "list the variables, with the values by -v if -persist",
+		},
+		{
+			name:     commandName("funcs"),
+			action:   actionFuncs,
+			document: "list the functions",
+		},
+		{
+			name:     commandName("types"),
+			action:   actionTypes,
+			document: "list the types",
+		},
+		{
+			name:     commandName("list"),
+			action:   actionList,
+			document: "list the declarations and statements with numbers",
+		},
+		{
+			name:     commandName("drop"),
+			action:   actionDrop,
+			arg:      "<n or name>",
+			document: "drop a declaration or statement",
+		},
 		{
 			name:     commandName("print"),
 			action:   actionPrint,
 			document: "print current source",
 		},
+		{
+			name:     commandName("printer"),
+			action:   actionPrinter,
+			complete: completePrinter,
+			arg:      "[<printer>]",
+			document: "show or change the printer of values",
+		},
+		{
+			name:     commandName("full"),
+			action:   actionFull,
+			document: "show the last result without truncation",
+		},
 		{
 			name:     commandName("w[rite]"),
 			action:   actionWrite,
@@ -57,11 +97,42 @@ func init() {
 			arg:      "[<file>]",
 			document: "write out current source",
 		},
+		{
+			name:     commandName("e[dit]"),
+			action:   actionEdit,
+			complete: completeEdit,
+			arg:      "[<func>]",
+			document: "edit the session or a function in $EDITOR",
+		},
+		{
+			name:     commandName("save"),
+			action:   actionSave,
+			arg:      "[<file>]",
+			document: "save the inputs to a session file",
+		},
+		{
+			name:     commandName("load"),
+			action:   actionLoad,
+			arg:      "<file>",
+			document: "load a session file into a fresh session",
+		},
 		{
 			name:     commandName("clear"),
 			action:   actionClear,
 			document: "clear the codes",
 		},
+		{
+			name:     commandName("undo"),
+			action:   actionUndo,
+			arg:      "[<n>]",
+			document: "undo the last n inputs",
+		},
+		{
+			name:     commandName("redo"),
+			action:   actionRedo,
+			arg:      "[<n>]",
+			document: "redo the undone inputs",
+		},
 		{
 			name:     commandName("d[oc]"),
 			action:   actionDoc,
@@ -69,6 +140,27 @@ func init() {
 			arg:      "<expr or pkg>",
 			document: "show documentation",
 		},
+		{
+			name:     commandName("time"),
+			action:   actionTime,
+			complete: completeOnOff,
+			arg:      "[on|off]",
+			document: "show elapsed time of build and run",
+		},
+		{
+			name:     commandName("set"),
+			action:   actionSet,
+			complete: completeSet,
+			arg:      "<key> [<value>]",
+			document: "show or change a setting",
+		},
+		{
+			name:     commandName("show"),
+			action:   actionShow,
+			complete: completeShow,
+			arg:      "[<key>]",
+			document: "show the settings",
+		},
 		{
 			name:     commandName("h[elp]"),
 			action:   actionHelp,
@@ -283,6 +375,68 @@ func actionType(s *Session, in string) error {
 		return errors.New("argument is required")
 	}
 
+	expr, typ, err := s.checkExpr(in)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(s.stdout, typeString(typ))
+	s.printInstances(expr)
+	return nil
+}
+
+// printInstances prints the generic functions and types instantiated in the
+// expression, along with the instantiated ones.
+func (s *Session) printInstances(expr ast.Expr) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		inst, ok := s.typeInfo.Instances[ident]
+		if !ok {
+			return true
+		}
+		obj := s.typeInfo.Uses[ident]
+		if obj == nil {
+			return true
+		}
+
+		var tparams *types.TypeParamList
+		generic, instance := obj.Type(), inst.Type
+		switch typ := obj.Type().(type) {
+		case *types.Named:
+			tparams = typ.TypeParams()
+			generic, instance = typ.Underlying(), inst.Type.Underlying()
+		case *types.Signature:
+			tparams = typ.TypeParams()
+		}
+		params := make([]string, tparams.Len())
+		for i := range params {
+			tparam := tparams.At(i)
+			params[i] = tparam.String() + " " + types.TypeString(tparam.Constraint(), qualifyByPath)
+		}
+		args := make([]string, inst.TypeArgs.Len())
+		for i := range args {
+			args[i] = types.TypeString(inst.TypeArgs.At(i), qualifyByPath)
+		}
+		if _, ok := obj.Type().(*types.Named); ok {
+			fmt.Fprintf(s.stdout, "%s[%s] %s\n", ident.Name, strings.Join(params, ", "), types.TypeString(generic, qualifyByPath))
+		} else {
+			fmt.Fprintf(s.stdout, "%s %s\n", ident.Name, types.TypeString(generic, qualifyByPath))
+		}
+		fmt.Fprintf(s.stdout, "%s[%s] %s\n", ident.Name, strings.Join(args, ", "), types.TypeString(instance, qualifyByPath))
+		return true
+	})
+}
+
+// exprType returns the type of the expression in the current session.
+func (s *Session) exprType(in string) (types.Type, error) {
+	_, typ, err := s.checkExpr(in)
+	return typ, err
+}
+
+// checkExpr type checks the expression in the current session.
+func (s *Session) checkExpr(in string) (ast.Expr, types.Type, error) {
 	s.clearQuickFix()
 
 	s.storeCode()
@@ -290,29 +444,39 @@ func actionType(s *Session, in string) error {
 
 	expr, err := s.evalExpr(in)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	s.typeInfo = types.Info{
-		Types:  make(map[ast.Expr]types.TypeAndValue),
-		Uses:   make(map[*ast.Ident]types.Object),
-		Defs:   make(map[*ast.Ident]types.Object),
-		Scopes: make(map[ast.Node]*types.Scope),
+		Types:     make(map[ast.Expr]types.TypeAndValue),
+		Uses:      make(map[*ast.Ident]types.Object),
+		Defs:      make(map[*ast.Ident]types.Object),
+		Scopes:    make(map[ast.Node]*types.Scope),
+		Instances: make(map[*ast.Ident]types.Instance),
+	}
+	var fixed bool
+	conf := *s.types
+	conf.Error = func(err error) {
+		if err, ok := err.(types.Error); ok && s.fixBlankResult(err) {
+			fixed = true
+		}
+	}
+	_, err = conf.Check("_tmp", s.fset, append(s.extraFiles, s.file), &s.typeInfo)
+	if fixed {
+		_, err = s.types.Check("_tmp", s.fset, append(s.extraFiles, s.file), &s.typeInfo)
 	}
-	_, err = s.types.Check("_tmp", s.fset, append(s.extraFiles, s.file), &s.typeInfo)
 	if err != nil {
 		debugf("typecheck error (ignored): %s", err)
 	}
 
 	typ := s.typeInfo.TypeOf(expr)
 	if typ == nil {
-		return fmt.Errorf("cannot get type: %v", expr)
+		return nil, nil, fmt.Errorf("cannot get type: %v", expr)
 	}
 	if typ, ok := typ.(*types.Basic); ok && typ.Kind() == types.Invalid {
-		return fmt.Errorf("cannot get type: %v", expr)
+		return nil, nil, fmt.Errorf("cannot get type: %v", expr)
 	}
-	fmt.Fprintf(s.stdout, "%v\n", typ)
-	return nil
+	return expr, typ, nil
 }
 
 func actionWrite(s *Session, filename string) error {
@@ -335,8 +499,96 @@ func actionWrite(s *Session, filename string) error {
 	return nil
 }
 
+func actionSave(s *Session, filename string) error {
+	if filename == "" {
+		filename = time.Now().Format("gore_session_20060102_150405.gore")
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := s.save(f); err != nil {
+		return err
+	}
+
+	infof("Session saved to %s", filename)
+
+	return nil
+}
+
+func actionLoad(s *Session, filename string) error {
+	if filename == "" {
+		return errors.New("argument is required")
+	}
+
+	// the session is kept when the file cannot be opened
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	snap, err := s.snapshot()
+	if err != nil {
+		return err
+	}
+
+	if err := s.init(); err != nil {
+		return err
+	}
+
+	// :undo brings back the session before loading
+	s.pushHistory(snap)
+	return s.evalReader(f, filename)
+}
+
 func actionClear(s *Session, _ string) error {
-	return s.init()
+	snap, err := s.snapshot()
+	if err != nil {
+		return err
+	}
+
+	if err := s.init(); err != nil {
+		return err
+	}
+
+	// record the history here not to log this input
+	s.pushHistory(snap)
+	return nil
+}
+
+func actionUndo(s *Session, arg string) error {
+	n, err := parseCount(arg)
+	if err != nil {
+		return err
+	}
+
+	return s.undo(n)
+}
+
+func actionRedo(s *Session, arg string) error {
+	n, err := parseCount(arg)
+	if err != nil {
+		return err
+	}
+
+	return s.redo(n)
+}
+
+func parseCount(arg string) (int, error) {
+	if arg == "" {
+		return 1, nil
+	}
+
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid count: %s", arg)
+	}
+
+	return n, nil
 }
 
 func actionDoc(s *Session, in string) error {
@@ -422,18 +674,18 @@ func actionDoc(s *Session, in string) error {
 	godoc := exec.Command("go", args...)
 	godoc.Dir = s.tempDir
 	godoc.Env = append(os.Environ(), "GO111MODULE=on")
-	ef := newErrFilter(s.stderr)
+	ef := newErrFilter(s.stderr, nil)
 	godoc.Stderr = ef
 	defer ef.Close()
 
 	// TODO just use PAGER?
-	if pagerCmd := os.Getenv("GORE_PAGER"); pagerCmd != "" {
+	if pagerCmd := strings.Fields(s.pager); len(pagerCmd) > 0 {
 		r, err := godoc.StdoutPipe()
 		if err != nil {
 			return err
 		}
 
-		pager := exec.Command(pagerCmd)
+		pager := exec.Command(pagerCmd[0], pagerCmd[1:]...)
 		pager.Stdin = r
 		pager.Stdout = s.stdout
 		pager.Stderr = s.stderr
@@ -454,6 +706,31 @@ func actionDoc(s *Session, in string) error {
 	return godoc.Run()
 }
 
+func actionTime(s *Session, arg string) error {
+	switch arg {
+	case "":
+		s.showTime = !s.showTime
+	case "on":
+		s.showTime = true
+	case "off":
+		s.showTime = false
+	default:
+		return fmt.Errorf("invalid argument: %s", arg)
+	}
+
+	return nil
+}
+
+func completeOnOff(_ *Session, prefix string) []string {
+	var result []string
+	for _, v := range []string{"on", "off"} {
+		if strings.HasPrefix(v, prefix) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
 func actionHelp(s *Session, _ string) error {
 	w := tabwriter.NewWriter(s.stdout, 0, 8, 4, ' ', 0)
 	for _, command := range commands {
@@ -2,6 +2,7 @@ package gore
 
 import (
 	"go/version"
+	"os"
 	"runtime"
 	"strings"
 	"testing"
@@ -53,6 +54,44 @@ type: cannot get type: fmt
 `, stderr.String())
 }
 
+func TestAction_Type_Generic(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		"func Map[T, U any](xs []T, f func(T) U) []U { var ys []U; for _, x := range xs { ys = append(ys, f(x)) }; return ys }",
+		":type Map",
+		":type Map[int, string]",
+		"type Pair[K comparable, V any] struct { k K; v V }",
+		":type Pair[string, int]{}",
+		"func Map[T any](xs []T) []T { return xs }",
+		"type Pair[K any] struct { k K }",
+		"func (p Pair[K]) Key() K { return p.k }",
+		"Pair[int]{1}.Key()",
+		":type Map([]int{1})",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `func[T, U any](xs []T, f func(T) U) []U
+func(xs []int, f func(int) string) []string
+Map func[T, U any](xs []T, f func(T) U) []U
+Map[int, string] func(xs []int, f func(int) string) []string
+Pair[string, int]
+Pair[K comparable, V any] struct{k K; v V}
+Pair[string, int] struct{k string; v int}
+1
+[]int
+Map func[T any](xs []T) []T
+Map[int] func(xs []int) []int
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
 func TestAction_Doc(t *testing.T) {
 	if version.Compare(runtime.Version(), "go1.24") < 0 {
 		t.Skipf("Skip on %s", runtime.Version())
@@ -151,6 +190,171 @@ func TestAction_Clear(t *testing.T) {
 	assert.Equal(t, "undefined: x\n", stderr.String())
 }
 
+func TestAction_Time(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		":time",
+		"1 + 1",
+		"1 + 2",
+		":time off",
+		"1 + 3",
+		":time foo",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, "2\n3\n4\n", stdout.String())
+	assert.Regexp(t, `^build: \S+, run: \S+
+build: \S+, run: \S+
+time: invalid argument: foo
+$`, stderr.String())
+}
+
+func TestAction_Undo(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		":undo",
+		"x := 10",
+		"1 + 2",
+		":import strings",
+		"type T int",
+		"func f(t T) string { return strings.Repeat(\"a\", int(t)) }",
+		"x = 20",
+		":undo 2",
+		"x",
+		":undo 2",
+		"x",
+		"strings.Repeat",
+		":redo",
+		":redo 2",
+		"f(T(x))",
+		":redo",
+		":undo 10",
+		":clear",
+		"x",
+		":undo",
+		"x",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `10
+3
+20
+10
+10
+"aaaaaaaaaa"
+10
+`, stdout.String())
+	assert.Equal(t, `undo: nothing to undo
+undefined: strings
+redo: nothing to redo
+undo: cannot undo 10 inputs, only 5 available
+undefined: x
+`, stderr.String())
+}
+
+func TestAction_SaveLoad(t *testing.T) {
+	var stdout, stderr strings.Builder
+	_ = newTempDir(t)
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		":import strings",
+		"x := 3",
+		"x",
+		"func f(s string, n int) string {\n\treturn strings.Repeat(s, n)\n}",
+		"y := f(\"a\", x)",
+		":save test.gore",
+		":load test.gore",
+		"f(\"b\", x)",
+		":undo",
+		"y",
+		":load",
+		":load foo.gore",
+		"x",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	content, err := os.ReadFile("test.gore")
+	require.NoError(t, err)
+	assert.Equal(t, `// gore session
+:import strings
+x := 3
+x
+func f(s string, n int) string {
+	return strings.Repeat(s, n)
+}
+y := f("a", x)
+`, string(content))
+
+	assert.Equal(t, `3
+3
+"aaa"
+3
+3
+"aaa"
+"bbb"
+"aaa"
+3
+`, stdout.String())
+	assert.Equal(t, `load: argument is required
+load: open foo.gore: no such file or directory
+`, stderr.String())
+}
+
+func TestAction_Save_Results(t *testing.T) {
+	var stdout, stderr strings.Builder
+	_ = newTempDir(t)
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		"1 + 2",
+		"y := 5",
+		"undefinedFn()",
+		"_1 + y",
+		":save test.gore",
+		":load test.gore",
+		"_4 * 2",
+		":vars",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	content, err := os.ReadFile("test.gore")
+	require.NoError(t, err)
+	assert.Equal(t, `// gore session
+1 + 2
+y := 5
+//gore:input 4
+_1 + y
+`, string(content))
+
+	assert.Equal(t, "3\n5\n8\n3\n5\n8\n16\ny int\n", stdout.String())
+	assert.Contains(t, stderr.String(), "undefined: undefinedFn")
+}
+
 func TestAction_Help(t *testing.T) {
 	var stdout, stderr strings.Builder
 	s, err := NewSession(&stdout, &stderr)
@@ -34,10 +34,25 @@ func TestSession_completeWord(t *testing.T) {
 	assert.Equal(t, []string{
 		"import ",
 		"type ",
+		"vars ",
+		"funcs",
+		"types",
+		"list",
+		"drop ",
 		"print",
+		"printer ",
+		"full",
 		"write ",
+		"edit ",
+		"save ",
+		"load ",
 		"clear",
+		"undo ",
+		"redo ",
 		"doc ",
+		"time ",
+		"set ",
+		"show ",
 		"help",
 		"quit",
 	}, cands)
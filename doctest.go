@@ -0,0 +1,179 @@
+package gore
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// example is an input and the expected output in a transcript of a session,
+// like the following:
+//
+//	gore> x := []int{1, 2}
+//	[]int{
+//	  1,
+//	  2,
+//	}
+//	gore> func f(xs []int) int {
+//	.....     return len(xs)
+//	..... }
+//	gore> f(x)
+//	2
+//
+// The output continues until the next prompt, a blank line or a code fence.
+// The blank lines in the output are written as <BLANKLINE>.
+type example struct {
+	line   int
+	input  string
+	output string
+}
+
+// blankLine is the line of an expected output which stands for a blank line.
+const blankLine = "<BLANKLINE>"
+
+func parseTranscript(r io.Reader) ([]*example, error) {
+	var examples []*example
+	var ex *example
+	var indent string
+	var hasOutput bool
+	sc := bufio.NewScanner(r)
+	for lineno := 1; sc.Scan(); lineno++ {
+		line := sc.Text()
+		if i := strings.Index(line, promptDefault); i >= 0 && strings.TrimSpace(line[:i]) == "" {
+			ex = &example{line: lineno, input: line[i+len(promptDefault):]}
+			examples = append(examples, ex)
+			indent, hasOutput = line[:i], false
+			continue
+		}
+		if ex == nil {
+			continue
+		}
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "```") {
+			ex = nil
+			continue
+		}
+		line = strings.TrimPrefix(line, indent)
+		if !hasOutput {
+			if in, ok := strings.CutPrefix(line, strings.TrimSpace(promptContinue)); ok {
+				ex.input += "\n" + strings.TrimPrefix(in, " ")
+				continue
+			}
+		}
+		if line == blankLine {
+			line = ""
+		}
+		ex.output += line + "\n"
+		hasOutput = true
+	}
+	return examples, sc.Err()
+}
+
+// runTest evaluates the inputs in the transcript file in a session, and
+// reports the examples whose outputs differ from the expected ones.
+func (g *Gore) runTest(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	examples, err := parseTranscript(f)
+	if err != nil {
+		return err
+	}
+
+	// the output of an input is the one shown at the terminal
+	var out syncBuffer
+	s, err := g.newSession(&out, &out)
+	defer s.Clear()
+	if err != nil {
+		return err
+	}
+
+	if err := g.include(s); err != nil {
+		return err
+	}
+
+	var failed int
+	for _, ex := range examples {
+		out.Reset()
+		err := s.Eval(ex.input)
+		if err == ErrQuit {
+			break
+		}
+		output := out.String()
+		if err == ErrContinue {
+			output = "(incomplete input)\n"
+		}
+		if expected, actual := normalizeOutput(ex.output), normalizeOutput(output); expected != actual {
+			failed++
+			fmt.Fprintf(g.outWriter, "%s:%d: %s\n", file, ex.line, strings.ReplaceAll(ex.input, "\n", "\n    "))
+			fmt.Fprintf(g.outWriter, "  expected:\n%s  got:\n%s", indentLines(expected), indentLines(actual))
+		}
+	}
+
+	fmt.Fprintf(g.outWriter, "%d passed, %d failed\n", len(examples)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d examples failed", failed, len(examples))
+	}
+	return nil
+}
+
+var escapeSequencePattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// normalizeOutput removes colors and trailing spaces of the output.
+func normalizeOutput(s string) string {
+	s = escapeSequencePattern.ReplaceAllString(s, "")
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRightFunc(line, func(c rune) bool {
+			return c == ' ' || c == '\t' || c == '\r'
+		})
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indentLines indents the lines of the output, writing the blank lines as in
+// the transcripts.
+func indentLines(s string) string {
+	if s == "" {
+		return ""
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" {
+			line = blankLine
+		}
+		lines[i] = "    " + line
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// syncBuffer is a buffer which can be written by the outputs of a process.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
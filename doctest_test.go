@@ -0,0 +1,109 @@
+package gore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTranscript(t *testing.T) {
+	src := "# Example\n" +
+		"\n" +
+		"```\n" +
+		"gore> x := []int{1, 2}\n" +
+		"[]int{1, 2}\n" +
+		"gore> func f(xs []int) int {\n" +
+		".....     return len(xs)\n" +
+		"..... }\n" +
+		"gore> f(x)\n" +
+		"2\n" +
+		"```\n" +
+		"\n" +
+		"    gore> :import fmt\n" +
+		"    gore> fmt.Println(\"a\\n\\nb\")\n" +
+		"    a\n" +
+		"    <BLANKLINE>\n" +
+		"    b\n" +
+		"\n" +
+		"Not an output.\n"
+
+	examples, err := parseTranscript(strings.NewReader(src))
+	require.NoError(t, err)
+	assert.Equal(t, []*example{
+		{line: 4, input: "x := []int{1, 2}", output: "[]int{1, 2}\n"},
+		{line: 6, input: "func f(xs []int) int {\n    return len(xs)\n}"},
+		{line: 9, input: "f(x)", output: "2\n"},
+		{line: 13, input: ":import fmt"},
+		{line: 14, input: `fmt.Println("a\n\nb")`, output: "a\n\nb\n"},
+	}, examples)
+}
+
+func TestGore_runTest(t *testing.T) {
+	testCases := []struct {
+		name, src, stdout, err string
+	}{
+		{
+			name: "pass",
+			src: `gore> :import fmt strings
+gore> x := strings.Repeat("a", 3)
+"aaa"
+gore> func f(s string) int {
+.....     return len(s)
+..... }
+gore> f(x)
+3
+gore> y
+undefined: y
+gore> fmt.Printf("%s\n\n%s\n", x, x)
+aaa
+<BLANKLINE>
+aaa
+9
+<nil>
+`,
+			stdout: "6 passed, 0 failed\n",
+		},
+		{
+			name: "fail",
+			src: `gore> 1 + 1
+3
+gore> 2 + 2
+4
+gore> func f() {
+`,
+			stdout: `transcript.txt:1: 1 + 1
+  expected:
+    3
+  got:
+    2
+transcript.txt:5: func f() {
+  expected:
+  got:
+    (incomplete input)
+1 passed, 2 failed
+`,
+			err: "2 of 3 examples failed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "transcript.txt")
+			require.NoError(t, os.WriteFile(file, []byte(tc.src), 0o644))
+
+			var stdout, stderr strings.Builder
+			err := New(TestFile(file), OutWriter(&stdout), ErrWriter(&stderr)).Run()
+			if tc.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.err)
+			}
+			assert.Equal(t, strings.ReplaceAll(tc.stdout, "transcript.txt", file), stdout.String())
+			assert.Equal(t, "", stderr.String())
+		})
+	}
+}
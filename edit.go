@@ -0,0 +1,115 @@
+package gore
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// editor returns the command to edit files.
+func editor() []string {
+	if cmd := strings.Fields(os.Getenv("EDITOR")); len(cmd) > 0 {
+		return cmd
+	}
+	return []string{"vi"}
+}
+
+// editFile opens the file with the editor, and reports whether it is changed.
+func (s *Session) editFile(name string, src []byte) ([]byte, bool, error) {
+	if err := os.WriteFile(name, src, 0o644); err != nil {
+		return nil, false, err
+	}
+	defer os.Remove(name)
+
+	args := editor()
+	cmd := exec.Command(args[0], append(args[1:], name)...)
+	cmd.Stdin = s.stdin
+	cmd.Stdout = s.stdout
+	cmd.Stderr = s.stderr
+	if err := cmd.Run(); err != nil {
+		return nil, false, err
+	}
+
+	edited, err := os.ReadFile(name)
+	if err != nil {
+		return nil, false, err
+	}
+	return edited, !bytes.Equal(edited, src), nil
+}
+
+func actionEdit(s *Session, name string) error {
+	if name == "" {
+		return s.editSession()
+	}
+	return s.editFunc(name)
+}
+
+// editSession edits the inputs of the session, and replays them into a fresh
+// session like :load.
+func (s *Session) editSession() error {
+	var buf bytes.Buffer
+	if err := s.save(&buf); err != nil {
+		return err
+	}
+
+	name := filepath.Join(s.tempDir, "gore_edit.gore")
+	src, changed, err := s.editFile(name, buf.Bytes())
+	if err != nil || !changed {
+		return err
+	}
+
+	snap, err := s.snapshot()
+	if err != nil {
+		return err
+	}
+
+	if err := s.init(); err != nil {
+		return err
+	}
+
+	// :undo brings back the session before editing
+	s.pushHistory(snap)
+	return s.evalReader(bytes.NewReader(src), name)
+}
+
+// editFunc edits the function declared in the session, and evaluates the
+// edited declarations as inputs.
+func (s *Session) editFunc(name string) error {
+	if name == "main" || strings.HasPrefix(name, "__gore_") {
+		return fmt.Errorf("function not found: %s", name)
+	}
+	var decl *ast.FuncDecl
+	for _, d := range s.file.Decls {
+		if d, ok := d.(*ast.FuncDecl); ok && funcKey(d) == name {
+			decl = d
+			break
+		}
+	}
+	if decl == nil {
+		return fmt.Errorf("function not found: %s", name)
+	}
+
+	name = filepath.Join(s.tempDir, "gore_edit.go")
+	src, changed, err := s.editFile(name, []byte(showNode(s.fset, decl)+"\n"))
+	if err != nil || !changed {
+		return err
+	}
+	return s.evalReader(bytes.NewReader(src), name)
+}
+
+func completeEdit(s *Session, prefix string) []string {
+	var result []string
+	for _, d := range s.file.Decls {
+		if d, ok := d.(*ast.FuncDecl); ok {
+			if key := funcKey(d); key != "main" && !strings.HasPrefix(key, "__gore_") &&
+				strings.HasPrefix(key, prefix) {
+				result = append(result, key)
+			}
+		}
+	}
+	return result
+}
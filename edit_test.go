@@ -0,0 +1,43 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_Edit(t *testing.T) {
+	t.Setenv("EDITOR", "sed -i s/foo/bar/")
+
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		"func f() string {\n\treturn \"foo\"\n}",
+		"f()",
+		":edit f",
+		"f()",
+		`x := "foo"`,
+		":edit",
+		"x + f()",
+		":edit g",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `"foo"
+"bar"
+"foo"
+"bar"
+"bar"
+"bar"
+"barbar"
+`, stdout.String())
+	assert.Equal(t, "edit: function not found: g\n", stderr.String())
+}
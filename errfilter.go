@@ -2,18 +2,30 @@ package gore
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"golang.org/x/text/transform"
 )
 
-func newErrFilter(w io.Writer) io.WriteCloser {
-	return transform.NewWriter(w, &errTransformer{})
+// newErrFilter returns the writer which removes the noises of go build from
+// the error outputs, and compacts the stack traces of the program. The frames
+// in gore_session.go are located by the function if it is not nil.
+func newErrFilter(w io.Writer, locate func(line int) string) io.WriteCloser {
+	return transform.NewWriter(w, &errTransformer{locate: locate})
 }
 
-type errTransformer struct{}
+type errTransformer struct {
+	locate func(line int) string
+	trace  bool   // in the frames of a goroutine
+	fn     []byte // the function of the frame waiting for the location
+}
 
-func (*errTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+func (t *errTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
 	var i int
 	for {
 		if atEOF {
@@ -26,7 +38,7 @@ func (*errTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, e
 				break
 			}
 		}
-		res := replaceErrMsg(src[:i+1])
+		res := t.replace(src[:i+1])
 		if nDst+len(res) > len(dst) {
 			err = transform.ErrShortDst
 			break
@@ -41,7 +53,70 @@ func (*errTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, e
 	return
 }
 
-func (*errTransformer) Reset() {}
+func (t *errTransformer) Reset() {
+	t.trace, t.fn = false, nil
+}
+
+var goroutinePattern = regexp.MustCompile(`^goroutine \d+ \[.*\]:\n?$`)
+
+// replace replaces the line of the error output, where the stack traces
+// following the goroutine headers are compacted.
+func (t *errTransformer) replace(p []byte) []byte {
+	if !t.trace {
+		t.trace = goroutinePattern.Match(p)
+		return replaceErrMsg(p)
+	}
+	switch {
+	case len(bytes.TrimSpace(p)) == 0:
+		t.trace, t.fn = false, nil
+		return p
+	case p[0] == '\t':
+		fn := t.fn
+		t.fn = nil
+		if fn == nil {
+			return p
+		}
+		return t.frame(fn, bytes.TrimSpace(p))
+	case bytes.HasPrefix(p, []byte("...")):
+		return p
+	default:
+		// the source is reused by the writer
+		t.fn = bytes.Clone(bytes.TrimSpace(p))
+		return nil
+	}
+}
+
+// frame returns the compact line of the frame, or nil if the frame is of the
+// runtime or the code generated by gore.
+func (t *errTransformer) frame(fn, loc []byte) []byte {
+	var created string
+	if name, ok := bytes.CutPrefix(fn, []byte("created by ")); ok {
+		created, fn = "created by ", name
+	} else if i := bytes.LastIndexByte(fn, '('); i > 0 && bytes.HasSuffix(fn, []byte(")")) {
+		fn = fn[:i]
+	}
+	if bytes.HasPrefix(fn, []byte("runtime.")) || bytes.Equal(fn, []byte("panic")) ||
+		bytes.Contains(fn, []byte("__gore_")) {
+		return nil
+	}
+
+	if i := bytes.LastIndex(loc, []byte(" +0x")); i > 0 {
+		loc = loc[:i]
+	}
+	file, line := filepath.Base(string(loc)), 0
+	if i := strings.LastIndexByte(file, ':'); i > 0 {
+		line, _ = strconv.Atoi(file[i+1:])
+		if name := file[:i]; name != "gore_session.go" && strings.HasPrefix(name, "gore_") {
+			return nil
+		}
+	}
+	if strings.HasPrefix(file, "gore_session.go:") && t.locate != nil {
+		if l := t.locate(line); l != "" {
+			file = l
+		}
+	}
+	return fmt.Appendf(nil, "    %s%s at %s\n", created, bytes.TrimPrefix(fn, []byte("main.")), file)
+}
 
 func replaceErrMsg(p []byte) []byte {
 	if bytes.HasPrefix(p, []byte("# command-line-arguments")) {
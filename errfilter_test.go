@@ -45,7 +45,7 @@ func TestErrFilter(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.id, func(t *testing.T) {
 			var out strings.Builder
-			w := newErrFilter(&out)
+			w := newErrFilter(&out, nil)
 			_, err := w.Write([]byte(tc.src))
 			require.NoError(t, err)
 			err = w.Close()
@@ -54,3 +54,53 @@ func TestErrFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestErrFilter_StackTrace(t *testing.T) {
+	src := `panic: runtime error: index out of range [5] with length 3
+
+goroutine 1 [running]:
+main.f(...)
+	/tmp/gore-123/gore_session.go:12
+main.__gore_p.func1({0x4a8f20, 0xc000012345})
+	/tmp/gore-123/gore_session.go:30 +0x1d
+main.__gore_call({0x4a8f20, 0xc000012345}, 0xc000012345)
+	/tmp/gore-123/gore_print.go:83 +0x47
+strings.Repeat({0x4b0ee5, 0x1}, 0xffffffffffffffff)
+	/usr/local/go/src/strings/strings.go:609 +0x5a5
+main.main()
+	/tmp/gore-123/gore_session.go:40 +0x1d
+
+goroutine 7 [chan send]:
+runtime.gopark(0x0?, 0x0?, 0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/proc.go:435 +0xce
+main.main.func1()
+	/tmp/gore-123/gore_session.go:41 +0x25
+created by main.main in goroutine 1
+	/tmp/gore-123/gore_session.go:99 +0x59
+`
+	var out strings.Builder
+	w := newErrFilter(&out, func(line int) string {
+		switch line {
+		case 12:
+			return "line 2 of func f"
+		case 40, 41:
+			return "input 3"
+		}
+		return ""
+	})
+	_, err := w.Write([]byte(src))
+	require.NoError(t, err)
+	err = w.Close()
+	require.NoError(t, err)
+	require.Equal(t, `panic: runtime error: index out of range [5] with length 3
+
+goroutine 1 [running]:
+    f at line 2 of func f
+    strings.Repeat at strings.go:609
+    main at input 3
+
+goroutine 7 [chan send]:
+    main.func1 at input 3
+    created by main in goroutine 1 at gore_session.go:99
+`, out.String())
+}
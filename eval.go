@@ -0,0 +1,183 @@
+package gore
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EvalResult is the result of an input evaluated by EvalContext.
+type EvalResult struct {
+	// Stdout and Stderr are the outputs of the input.
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+	// Type is the type of the input if it is an expression.
+	Type string `json:"type,omitempty"`
+	// Diagnostics are the errors reported by the compiler.
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// Diagnostic is an error reported by the compiler.
+type Diagnostic struct {
+	// File is the name of the file, which is gore_session.go for the inputs
+	// or the file included by -context option.
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// Input is the number of the input which the error is in, or zero if it
+	// is not in the inputs evaluated in the main function.
+	Input   int    `json:"input,omitempty"`
+	Message string `json:"message"`
+}
+
+// EvalContext evaluates the input like Eval, but returns the outputs instead
+// of writing them to the writers of the session. The program is killed when
+// the context is done, and the input is discarded. The error is the one Eval
+// returns, or the error of the context if it is done.
+func (s *Session) EvalContext(ctx context.Context, in string) (*EvalResult, error) {
+	var result EvalResult
+	if !strings.HasPrefix(strings.TrimSpace(in), ":") {
+		if typ, err := s.exprType(in); err == nil {
+			result.Type = typeString(typ)
+		}
+	}
+
+	var stdout, stderr syncBuffer
+	origStdout, origStderr := s.stdout, s.stderr
+	s.stdout, s.stderr = &stdout, &stderr
+	s.diagnostics = nil
+	err := s.eval(ctx, in)
+	s.stdout, s.stderr = origStdout, origStderr
+
+	result.Stdout, result.Stderr = stdout.String(), stderr.String()
+	result.Diagnostics = s.diagnostics
+	if ctxErr := ctx.Err(); ctxErr != nil && err != nil {
+		err = ctxErr
+	}
+	return &result, err
+}
+
+var diagnosticPattern = regexp.MustCompile(`(?m)^(\S+\.go):(\d+):(\d+): (.*)$`)
+
+// parseDiagnostics parses the output of go build, looking up the inputs
+// of the errors in the main function.
+func (s *Session) parseDiagnostics(output string) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, m := range diagnosticPattern.FindAllStringSubmatch(output, -1) {
+		line, _ := strconv.Atoi(m[2])
+		column, _ := strconv.Atoi(m[3])
+		diagnostics = append(diagnostics, Diagnostic{
+			File:    filepath.Base(m[1]),
+			Line:    line,
+			Column:  column,
+			Message: m[4],
+		})
+	}
+
+	if len(diagnostics) == 0 {
+		return diagnostics
+	}
+
+	tf, _, main := s.builtFile()
+	if main == nil {
+		return diagnostics
+	}
+	for i := range diagnostics {
+		d := &diagnostics[i]
+		if d.File != "gore_session.go" || d.Line < 1 || d.Line > tf.LineCount() {
+			continue
+		}
+		// the statements may be printed on a line
+		pos := tf.LineStart(d.Line) + token.Pos(d.Column-1)
+		for j, stmt := range main.Body.List {
+			if stmt.Pos() <= pos && pos < stmt.End() {
+				d.Input = s.inputOf(j)
+				break
+			}
+		}
+	}
+	return diagnostics
+}
+
+// builtFile parses the built file, and returns the main function if its
+// statements correspond to the ones of the session.
+func (s *Session) builtFile() (*token.File, *ast.File, *ast.FuncDecl) {
+	// the built file is parsed since the positions of the session are not the
+	// ones of the printed source
+	source, err := os.ReadFile(s.tempFilePath)
+	if err != nil {
+		return nil, nil, nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "gore_session.go", source, parser.Mode(0))
+	if err != nil {
+		return nil, nil, nil
+	}
+	tf := fset.File(file.Pos())
+
+	// the statements are rewritten in the persistent mode
+	if s.persist {
+		return tf, file, nil
+	}
+	main, ok := file.Scope.Lookup("main").Decl.(*ast.FuncDecl)
+	if !ok || len(main.Body.List) != len(s.mainBody.List) {
+		return tf, file, nil
+	}
+	return tf, file, main
+}
+
+// frameLocator returns the function which locates the line of the built file
+// in the inputs or the functions of the session, for the stack traces. The
+// file is parsed when a frame in it is found first.
+func (s *Session) frameLocator() func(line int) string {
+	var parsed bool
+	var tf *token.File
+	var file *ast.File
+	var main *ast.FuncDecl
+	return func(line int) string {
+		if !parsed {
+			tf, file, main = s.builtFile()
+			parsed = true
+		}
+		if file == nil || line < 1 || line > tf.LineCount() {
+			return ""
+		}
+		if main != nil {
+			for j, stmt := range main.Body.List {
+				if tf.Line(stmt.Pos()) <= line && line <= tf.Line(stmt.End()) {
+					input := s.inputOf(j)
+					if input == 0 {
+						return ""
+					}
+					// the line is counted from the first statement of the input
+					i := j
+					for i > 0 && s.inputOf(i-1) == input {
+						i--
+					}
+					return lineOf(line-tf.Line(main.Body.List[i].Pos())+1, fmt.Sprintf("input %d", input))
+				}
+			}
+		}
+		for _, decl := range file.Decls {
+			if decl, ok := decl.(*ast.FuncDecl); ok && funcKey(decl) != "main" &&
+				tf.Line(decl.Pos()) <= line && line <= tf.Line(decl.End()) {
+				return lineOf(line-tf.Line(decl.Pos())+1, "func "+funcKey(decl))
+			}
+		}
+		return ""
+	}
+}
+
+func lineOf(line int, name string) string {
+	if line == 1 {
+		return name
+	}
+	return fmt.Sprintf("line %d of %s", line, name)
+}
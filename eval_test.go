@@ -0,0 +1,98 @@
+package gore
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_EvalContext(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	res, err := s.EvalContext(ctx, `x := "foo"`)
+	require.NoError(t, err)
+	assert.Equal(t, &EvalResult{Stdout: "\"foo\"\n"}, res)
+
+	res, err = s.EvalContext(ctx, "len(x)")
+	require.NoError(t, err)
+	assert.Equal(t, &EvalResult{Stdout: "3\n", Type: "int"}, res)
+
+	res, err = s.EvalContext(ctx, "y := x + 1")
+	require.Equal(t, ErrCmdRun, err)
+	assert.Equal(t, "", res.Stdout)
+	assert.Contains(t, res.Stderr, "mismatched types")
+	require.Len(t, res.Diagnostics, 1)
+	assert.Equal(t, "gore_session.go", res.Diagnostics[0].File)
+	assert.Equal(t, 3, res.Diagnostics[0].Input)
+	assert.Contains(t, res.Diagnostics[0].Message, "mismatched types")
+
+	res, err = s.EvalContext(ctx, ":type x")
+	require.NoError(t, err)
+	assert.Equal(t, &EvalResult{Stdout: "string\n"}, res)
+
+	_, err = s.EvalContext(ctx, "type T struct{}")
+	require.NoError(t, err)
+	_, err = s.EvalContext(ctx, "type T int")
+	require.NoError(t, err)
+	res, err = s.EvalContext(ctx, "[]T{1}")
+	require.NoError(t, err)
+	assert.Equal(t, &EvalResult{Stdout: "[]main.T{1}\n", Type: "[]T"}, res)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err = s.EvalContext(ctx, ":import time")
+	require.NoError(t, err)
+	_, err = s.EvalContext(ctx, "time.Sleep(time.Hour)")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the canceled input is discarded
+	res, err = s.EvalContext(context.Background(), "x")
+	require.NoError(t, err)
+	assert.Equal(t, "\"foo\"\n", res.Stdout)
+
+	assert.Equal(t, "", stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestSession_EvalContext_Subprocess(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = s.EvalContext(ctx, ":import fmt os os/exec")
+	require.NoError(t, err)
+
+	// the subprocess holding the output does not block the session
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	res, err := s.EvalContext(ctx, `cmd := exec.Command("sleep", "60"); cmd.Stdout = os.Stdout; cmd.Start(); fmt.Fprintln(os.Stderr, cmd.Process.Pid); cmd.Wait()`)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 30*time.Second)
+
+	// the subprocess is killed with the program
+	pid, err := strconv.Atoi(strings.TrimSpace(res.Stderr))
+	require.NoError(t, err)
+	p, err := os.FindProcess(pid)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return p.Signal(syscall.Signal(0)) != nil
+	}, 10*time.Second, 100*time.Millisecond)
+
+	res, err = s.EvalContext(context.Background(), "1 + 2")
+	require.NoError(t, err)
+	assert.Equal(t, "3\n", res.Stdout)
+}
@@ -3,6 +3,7 @@ module github.com/x-motemen/gore
 go 1.26
 
 require (
+	github.com/go-zeromq/zmq4 v0.17.0
 	github.com/motemen/go-quickfix v0.0.0-20250224075427-39bb724d71b7
 	github.com/peterh/liner v1.2.2
 	github.com/stretchr/testify v1.8.1
@@ -15,6 +16,7 @@ require (
 require (
 	github.com/clipperhouse/uax29/v2 v2.7.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-zeromq/goczmq/v4 v4.2.2 // indirect
 	github.com/mattn/go-runewidth v0.0.24 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/segmentio/asm v1.2.1 // indirect
@@ -54,6 +54,12 @@ func (s *Session) listModuleDirectives() []string {
 		// only the first printer is checked (assuming printerPkgs[1] is fmt)
 		break
 	}
+	// the versions of the other printers are specified if they are available
+	for _, p := range printers {
+		if p.module.path != "" && lookupGoModule(p.module.path, p.module.version) {
+			directives = append(directives, "require "+p.module.path+" "+p.module.version)
+		}
+	}
 	modules, err := goListAll()
 	if err != nil {
 		return directives
@@ -1,11 +1,14 @@
 package gore
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // Version of gore.
@@ -14,8 +17,22 @@ const Version = "0.6.2"
 // Gore ...
 type Gore struct {
 	autoImport           bool
+	persist              bool
 	extFiles             string
 	packageName          string
+	loadFile             string
+	code                 string
+	scriptFile           string
+	testFile             string
+	kernelFile           string
+	serve                bool
+	serveAddr            string
+	timeout              time.Duration
+	wait                 time.Duration
+	memoryLimit          string
+	cpuLimit             time.Duration
+	printer              string
+	showType             bool
 	outWriter, errWriter io.Writer
 }
 
@@ -30,30 +47,55 @@ func New(opts ...Option) *Gore {
 
 // Run ...
 func (g *Gore) Run() error {
-	s, err := NewSession(g.outWriter, g.errWriter)
+	if g.testFile != "" {
+		return g.runTest(g.testFile)
+	}
+	if g.kernelFile != "" {
+		return g.runKernel(g.kernelFile)
+	}
+	if g.serve {
+		return g.runServer(context.Background(), g.serveAddr)
+	}
+
+	s, err := g.newSession(g.outWriter, g.errWriter)
 	defer s.Clear()
 	if err != nil {
 		return err
 	}
-	s.autoImport = g.autoImport
 
-	if err := s.initCompleter(); err != nil {
-		debugf("failed to initialize gopls completer: %s", err)
-	}
+	// evaluate the code non-interactively if specified or piped
+	interactive := g.code == "" && g.scriptFile == "" && isTerminal(os.Stdin)
 
-	fmt.Fprintf(g.errWriter, "gore version %s  :help for help\n", Version)
+	if interactive {
+		if err := s.initCompleter(); err != nil {
+			debugf("failed to initialize gopls completer: %s", err)
+		}
 
-	if g.extFiles != "" {
-		extFiles := strings.Split(g.extFiles, ",")
-		s.includeFiles(extFiles)
+		fmt.Fprintf(g.errWriter, "gore version %s  :help for help\n", Version)
 	}
 
-	if g.packageName != "" {
-		if err := s.includePackage(g.packageName); err != nil {
-			return err
+	if err := g.include(s); err != nil {
+		return err
+	}
+
+	if g.loadFile != "" {
+		if err := s.load(g.loadFile); err != nil {
+			if !interactive {
+				return err
+			}
+			errorf("%s", err)
 		}
 	}
 
+	switch {
+	case g.code != "":
+		return s.evalReader(strings.NewReader(g.code), "-e")
+	case g.scriptFile != "":
+		return s.load(g.scriptFile)
+	case !interactive:
+		return s.evalReader(os.Stdin, "<stdin>")
+	}
+
 	rl := newContLiner()
 	defer rl.Close()
 
@@ -83,6 +125,7 @@ func (g *Gore) Run() error {
 	})
 
 	for {
+		rl.prompt = s.prompt
 		in, err := rl.Prompt()
 		if err != nil {
 			if err == io.EOF {
@@ -101,7 +144,13 @@ func (g *Gore) Run() error {
 			continue
 		}
 
-		err = s.Eval(in)
+		// Ctrl-C kills the program instead of the session
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		err = s.eval(ctx, in)
+		if ctx.Err() != nil {
+			fmt.Fprintln(g.errWriter, "interrupted")
+		}
+		stop()
 		if err != nil {
 			if err == ErrContinue {
 				continue
@@ -136,6 +185,58 @@ func (g *Gore) Run() error {
 	return nil
 }
 
+// newSession creates a session configured with the options.
+func (g *Gore) newSession(stdout, stderr io.Writer) (*Session, error) {
+	s, err := NewSession(stdout, stderr)
+	if err != nil {
+		return s, err
+	}
+	s.autoImport = g.autoImport
+	s.showType = g.showType
+	s.persist = g.persist
+	s.timeout = g.timeout
+	s.wait = g.wait
+	if g.memoryLimit != "" {
+		if err := s.set("memlimit", g.memoryLimit); err != nil {
+			return s, err
+		}
+	}
+	if g.cpuLimit > 0 {
+		if err := s.set("cpulimit", g.cpuLimit.String()); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// include includes the files and the package specified by the options.
+func (g *Gore) include(s *Session) error {
+	if g.extFiles != "" {
+		extFiles := strings.Split(g.extFiles, ",")
+		s.includeFiles(extFiles)
+	}
+
+	if g.packageName != "" {
+		if err := s.includePackage(g.packageName); err != nil {
+			return err
+		}
+	}
+
+	// the printer may be a function in the included files
+	if g.printer != "" {
+		if err := s.set("printer", g.printer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
 func homeDir() (home string, err error) {
 	home = os.Getenv("GORE_HOME")
 	if home != "" {
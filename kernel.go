@@ -0,0 +1,538 @@
+package gore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-zeromq/zmq4"
+)
+
+// The kernel speaks the Jupyter messaging protocol, so that the session can be
+// used from the notebooks. See the following document for the protocol.
+// https://jupyter-client.readthedocs.io/en/stable/messaging.html
+const kernelProtocolVersion = "5.3"
+
+const kernelDelimiter = "<IDS|MSG>"
+
+// connectionInfo is the content of the connection file given by the frontend.
+type connectionInfo struct {
+	Transport       string `json:"transport"`
+	IP              string `json:"ip"`
+	ShellPort       int    `json:"shell_port"`
+	IOPubPort       int    `json:"iopub_port"`
+	StdinPort       int    `json:"stdin_port"`
+	ControlPort     int    `json:"control_port"`
+	HBPort          int    `json:"hb_port"`
+	SignatureScheme string `json:"signature_scheme"`
+	Key             string `json:"key"`
+}
+
+func readConnectionInfo(name string) (*connectionInfo, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var info connectionInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if info.Transport == "" {
+		info.Transport = "tcp"
+	}
+	if info.Key != "" && info.SignatureScheme != "hmac-sha256" {
+		return nil, fmt.Errorf("%s: unsupported signature scheme: %s", name, info.SignatureScheme)
+	}
+	return &info, nil
+}
+
+func (info *connectionInfo) endpoint(port int) string {
+	if info.Transport == "ipc" {
+		return fmt.Sprintf("ipc://%s-%d", info.IP, port)
+	}
+	return fmt.Sprintf("%s://%s:%d", info.Transport, info.IP, port)
+}
+
+type kernelHeader struct {
+	MsgID    string `json:"msg_id"`
+	Session  string `json:"session"`
+	Username string `json:"username"`
+	Date     string `json:"date"`
+	MsgType  string `json:"msg_type"`
+	Version  string `json:"version"`
+}
+
+type kernelMessage struct {
+	identities [][]byte
+	header     kernelHeader
+	parent     json.RawMessage
+	content    json.RawMessage
+}
+
+type kernel struct {
+	session   *Session
+	key       []byte
+	id        string
+	shell     zmq4.Socket
+	control   zmq4.Socket
+	iopub     zmq4.Socket
+	stdin     zmq4.Socket
+	hb        zmq4.Socket
+	cancel    context.CancelFunc
+	handling  sync.Mutex // serializes the requests from the sockets
+	mu        sync.Mutex // guards iopub, parent and interrupt
+	parent    *kernelMessage
+	interrupt context.CancelFunc // cancels the running execution
+	count     int
+	shutdown  bool
+}
+
+// runKernel runs the session as a Jupyter kernel until the frontend requests
+// to shut down.
+func (g *Gore) runKernel(connectionFile string) error {
+	info, err := readConnectionInfo(connectionFile)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	k := &kernel{
+		key:     []byte(info.Key),
+		id:      newMessageID(),
+		shell:   zmq4.NewRouter(ctx),
+		control: zmq4.NewRouter(ctx),
+		iopub:   zmq4.NewPub(ctx),
+		stdin:   zmq4.NewRouter(ctx),
+		hb:      zmq4.NewRep(ctx),
+		cancel:  cancel,
+	}
+	for socket, port := range map[zmq4.Socket]int{
+		k.shell:   info.ShellPort,
+		k.control: info.ControlPort,
+		k.iopub:   info.IOPubPort,
+		k.stdin:   info.StdinPort,
+		k.hb:      info.HBPort,
+	} {
+		defer socket.Close()
+		if err := socket.Listen(info.endpoint(port)); err != nil {
+			return fmt.Errorf("listen %s: %w", info.endpoint(port), err)
+		}
+	}
+
+	s, err := g.newSession(&kernelStream{k, "stdout"}, &kernelStream{k, "stderr"})
+	defer s.Clear()
+	if err != nil {
+		return err
+	}
+	k.session = s
+
+	if err := s.initCompleter(); err != nil {
+		debugf("failed to initialize gopls completer: %s", err)
+	}
+
+	if err := g.include(s); err != nil {
+		return err
+	}
+
+	// the frontends send SIGINT instead of interrupt_request unless the
+	// interrupt_mode of the kernel spec is message
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer func() {
+		signal.Stop(interrupts)
+		close(interrupts)
+	}()
+	go func() {
+		for range interrupts {
+			debugf("kernel :: interrupted by signal")
+			k.interruptExecution()
+		}
+	}()
+
+	go k.heartbeat()
+	go k.serve(k.control)
+	k.serve(k.shell)
+
+	k.handling.Lock()
+	defer k.handling.Unlock()
+	if k.shutdown {
+		return nil
+	}
+	return errors.New("kernel stopped unexpectedly")
+}
+
+func (k *kernel) heartbeat() {
+	for {
+		msg, err := k.hb.Recv()
+		if err != nil {
+			return
+		}
+		if err := k.hb.Send(msg); err != nil {
+			return
+		}
+	}
+}
+
+// interruptExecution cancels the running execution if any.
+func (k *kernel) interruptExecution() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.interrupt != nil {
+		k.interrupt()
+	}
+}
+
+// serve handles the requests from the socket until the kernel shuts down.
+// The requests from the shell and control sockets are handled one by one,
+// since the session is not safe for concurrent use.
+func (k *kernel) serve(socket zmq4.Socket) {
+	for {
+		msg, err := socket.Recv()
+		if err != nil {
+			debugf("kernel :: recv: %s", err)
+			k.cancel()
+			return
+		}
+
+		req, err := k.parseMessage(msg.Frames)
+		if err != nil {
+			debugf("kernel :: %s", err)
+			continue
+		}
+
+		// the execution is interrupted while it is being handled
+		if req.header.MsgType == "interrupt_request" {
+			debugf("kernel :: %s", req.header.MsgType)
+			k.interruptExecution()
+			k.reply(socket, req, map[string]any{"status": "ok"})
+			continue
+		}
+
+		k.handling.Lock()
+		k.mu.Lock()
+		k.parent = req
+		k.mu.Unlock()
+
+		k.publish("status", map[string]any{"execution_state": "busy"})
+		k.handle(socket, req)
+		k.publish("status", map[string]any{"execution_state": "idle"})
+
+		shutdown := k.shutdown
+		k.handling.Unlock()
+		if shutdown {
+			k.cancel()
+			return
+		}
+	}
+}
+
+func (k *kernel) handle(socket zmq4.Socket, req *kernelMessage) {
+	debugf("kernel :: %s", req.header.MsgType)
+	var content any
+	switch req.header.MsgType {
+	case "kernel_info_request":
+		content = map[string]any{
+			"status":                 "ok",
+			"protocol_version":       kernelProtocolVersion,
+			"implementation":         "gore",
+			"implementation_version": Version,
+			"language_info": map[string]any{
+				"name":           "go",
+				"version":        strings.TrimPrefix(runtime.Version(), "go"),
+				"mimetype":       "text/x-go",
+				"file_extension": ".go",
+			},
+			"banner":     fmt.Sprintf("gore version %s  :help for help", Version),
+			"help_links": []any{},
+		}
+	case "execute_request":
+		content = k.execute(req)
+	case "complete_request":
+		content = k.complete(req)
+	case "inspect_request":
+		content = k.inspect(req)
+	case "is_complete_request":
+		content = k.isComplete(req)
+	case "shutdown_request":
+		var r struct {
+			Restart bool `json:"restart"`
+		}
+		_ = json.Unmarshal(req.content, &r)
+		content = map[string]any{"status": "ok", "restart": r.Restart}
+		k.shutdown = true
+	default:
+		debugf("kernel :: unsupported message: %s", req.header.MsgType)
+		return
+	}
+
+	k.reply(socket, req, content)
+}
+
+func (k *kernel) reply(socket zmq4.Socket, req *kernelMessage, content any) {
+	reply := strings.TrimSuffix(req.header.MsgType, "_request") + "_reply"
+	if err := socket.Send(zmq4.NewMsgFrom(k.frames(req.identities, reply, req, content)...)); err != nil {
+		debugf("kernel :: send: %s", err)
+	}
+}
+
+func (k *kernel) execute(req *kernelMessage) map[string]any {
+	var r struct {
+		Code   string `json:"code"`
+		Silent bool   `json:"silent"`
+	}
+	_ = json.Unmarshal(req.content, &r)
+
+	if !r.Silent {
+		k.count++
+		k.publish("execute_input", map[string]any{"code": r.Code, "execution_count": k.count})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	k.mu.Lock()
+	k.interrupt = cancel
+	k.mu.Unlock()
+	defer func() {
+		k.mu.Lock()
+		k.interrupt = nil
+		k.mu.Unlock()
+	}()
+
+	// a cell can hold multiple inputs like a session file
+	err := k.session.evalReaderContext(ctx, strings.NewReader(r.Code), fmt.Sprintf("In [%d]", k.count))
+	if err == nil {
+		return map[string]any{
+			"status":           "ok",
+			"execution_count":  k.count,
+			"payload":          []any{},
+			"user_expressions": map[string]any{},
+		}
+	}
+
+	ename := "Error"
+	if ctx.Err() != nil {
+		ename, err = "Interrupted", errors.New("interrupted")
+	} else if errors.Is(err, ErrCmdRun) {
+		ename = "CommandFailed"
+	}
+	content := map[string]any{
+		"ename":     ename,
+		"evalue":    err.Error(),
+		"traceback": []string{err.Error()},
+	}
+	k.publish("error", content)
+	content["status"] = "error"
+	content["execution_count"] = k.count
+	return content
+}
+
+func (k *kernel) complete(req *kernelMessage) map[string]any {
+	var r struct {
+		Code      string `json:"code"`
+		CursorPos int    `json:"cursor_pos"`
+	}
+	_ = json.Unmarshal(req.content, &r)
+
+	// the cursor position is in unicode code points
+	pos := runeOffset(r.Code, r.CursorPos)
+	start := strings.LastIndexByte(r.Code[:pos], '\n') + 1
+	end := strings.IndexByte(r.Code[pos:], '\n')
+	if end < 0 {
+		end = len(r.Code)
+	} else {
+		end += pos
+	}
+
+	head, candidates, _ := k.session.completeWord(r.Code[start:end], pos-start)
+	if candidates == nil {
+		candidates = []string{}
+	}
+	return map[string]any{
+		"status":       "ok",
+		"matches":      candidates,
+		"cursor_start": utf8.RuneCountInString(r.Code[:start]) + utf8.RuneCountInString(head),
+		"cursor_end":   r.CursorPos,
+		"metadata":     map[string]any{},
+	}
+}
+
+func (k *kernel) inspect(req *kernelMessage) map[string]any {
+	var r struct {
+		Code      string `json:"code"`
+		CursorPos int    `json:"cursor_pos"`
+	}
+	_ = json.Unmarshal(req.content, &r)
+
+	content := map[string]any{"status": "ok", "found": false, "data": map[string]any{}, "metadata": map[string]any{}}
+	expr := exprAt(r.Code, runeOffset(r.Code, r.CursorPos))
+	if expr == "" {
+		return content
+	}
+
+	// show the outputs of :type and :doc commands
+	s := k.session
+	typ, typeErr := s.captureOutput(func() error { return actionType(s, expr) })
+	doc, docErr := s.captureOutput(func() error { return actionDoc(s, expr) })
+	if typeErr != nil && docErr != nil {
+		return content
+	}
+
+	var text string
+	if typeErr == nil {
+		text = typ + "\n"
+	}
+	if docErr == nil {
+		text += doc
+	}
+	content["found"] = true
+	content["data"] = map[string]any{"text/plain": strings.TrimSpace(text)}
+	return content
+}
+
+func (k *kernel) isComplete(req *kernelMessage) map[string]any {
+	var r struct {
+		Code string `json:"code"`
+	}
+	_ = json.Unmarshal(req.content, &r)
+
+	switch depth := countDepth(r.Code); {
+	case depth < 0:
+		return map[string]any{"status": "invalid"}
+	case depth > 0:
+		return map[string]any{"status": "incomplete", "indent": strings.Repeat(indent, depth)}
+	default:
+		return map[string]any{"status": "complete"}
+	}
+}
+
+// publish broadcasts the message to the frontends, as a result of the request
+// being handled.
+func (k *kernel) publish(msgType string, content any) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	frames := k.frames([][]byte{[]byte(msgType)}, msgType, k.parent, content)
+	if err := k.iopub.Send(zmq4.NewMsgFrom(frames...)); err != nil {
+		debugf("kernel :: publish: %s", err)
+	}
+}
+
+func (k *kernel) frames(identities [][]byte, msgType string, parent *kernelMessage, content any) [][]byte {
+	header, _ := json.Marshal(kernelHeader{
+		MsgID:    newMessageID(),
+		Session:  k.id,
+		Username: "gore",
+		Date:     time.Now().UTC().Format(time.RFC3339Nano),
+		MsgType:  msgType,
+		Version:  kernelProtocolVersion,
+	})
+	parentHeader := []byte("{}")
+	if parent != nil {
+		parentHeader, _ = json.Marshal(parent.header)
+	}
+	body, _ := json.Marshal(content)
+	parts := [][]byte{header, parentHeader, []byte("{}"), body}
+
+	frames := append(identities[:len(identities):len(identities)], []byte(kernelDelimiter), k.sign(parts))
+	return append(frames, parts...)
+}
+
+func (k *kernel) parseMessage(frames [][]byte) (*kernelMessage, error) {
+	for i, frame := range frames {
+		if string(frame) != kernelDelimiter {
+			continue
+		}
+		if len(frames) < i+6 {
+			return nil, errors.New("malformed message")
+		}
+		if !hmac.Equal(frames[i+1], k.sign(frames[i+2:i+6])) {
+			return nil, errors.New("invalid signature")
+		}
+
+		msg := &kernelMessage{identities: frames[:i], parent: frames[i+3], content: frames[i+5]}
+		if err := json.Unmarshal(frames[i+2], &msg.header); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+	return nil, errors.New("delimiter not found")
+}
+
+func (k *kernel) sign(parts [][]byte) []byte {
+	if len(k.key) == 0 {
+		return []byte{}
+	}
+	mac := hmac.New(sha256.New, k.key)
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	return []byte(hex.EncodeToString(mac.Sum(nil)))
+}
+
+// kernelStream sends the outputs of the session to the frontends.
+type kernelStream struct {
+	kernel *kernel
+	name   string
+}
+
+func (w *kernelStream) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	w.kernel.publish("stream", map[string]any{"name": w.name, "text": string(p)})
+	return len(p), nil
+}
+
+func newMessageID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// runeOffset converts the offset in unicode code points to the one in bytes.
+func runeOffset(s string, n int) int {
+	for i := range s {
+		if n <= 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
+
+// exprAt returns the selector expression at the position, like "json.Marshal".
+func exprAt(s string, pos int) string {
+	isExprChar := func(c rune) bool {
+		return c == '_' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c)
+	}
+	start := strings.LastIndexFunc(s[:pos], func(c rune) bool { return !isExprChar(c) })
+	if start < 0 {
+		start = 0
+	} else {
+		_, size := utf8.DecodeRuneInString(s[start:])
+		start += size
+	}
+	end := strings.IndexFunc(s[pos:], func(c rune) bool { return !isExprChar(c) })
+	if end < 0 {
+		end = len(s)
+	} else {
+		end += pos
+	}
+	return strings.Trim(s[start:end], ".")
+}
@@ -0,0 +1,187 @@
+package gore
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-zeromq/zmq4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestGore_runKernel(t *testing.T) {
+	info := connectionInfo{
+		Transport:       "tcp",
+		IP:              "127.0.0.1",
+		ShellPort:       freePort(t),
+		IOPubPort:       freePort(t),
+		StdinPort:       freePort(t),
+		ControlPort:     freePort(t),
+		HBPort:          freePort(t),
+		SignatureScheme: "hmac-sha256",
+		Key:             "secret",
+	}
+	b, err := json.Marshal(info)
+	require.NoError(t, err)
+	connectionFile := filepath.Join(t.TempDir(), "kernel.json")
+	require.NoError(t, os.WriteFile(connectionFile, b, 0o600))
+
+	var stdout, stderr strings.Builder
+	done := make(chan error)
+	go func() {
+		done <- New(KernelFile(connectionFile), OutWriter(&stdout), ErrWriter(&stderr)).Run()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	shell := zmq4.NewDealer(ctx)
+	defer shell.Close()
+	require.NoError(t, shell.Dial(info.endpoint(info.ShellPort)))
+	iopub := zmq4.NewSub(ctx)
+	defer iopub.Close()
+	require.NoError(t, iopub.Dial(info.endpoint(info.IOPubPort)))
+	require.NoError(t, iopub.SetOption(zmq4.OptionSubscribe, "stream"))
+
+	client := &kernel{key: []byte(info.Key), id: "test"}
+	request := func(msgType string, content any) map[string]any {
+		t.Helper()
+		req := &kernelMessage{header: kernelHeader{MsgType: msgType}}
+		require.NoError(t, shell.Send(zmq4.NewMsgFrom(client.frames(nil, msgType, req, content)...)))
+		msg, err := shell.Recv()
+		require.NoError(t, err)
+		reply, err := client.parseMessage(msg.Frames)
+		require.NoError(t, err)
+		assert.Equal(t, strings.TrimSuffix(msgType, "_request")+"_reply", reply.header.MsgType)
+		var result map[string]any
+		require.NoError(t, json.Unmarshal(reply.content, &result))
+		return result
+	}
+
+	reply := request("kernel_info_request", map[string]any{})
+	assert.Equal(t, "ok", reply["status"])
+	assert.Equal(t, "gore", reply["implementation"])
+
+	reply = request("execute_request", map[string]any{"code": "x := 40\nx + 2"})
+	assert.Equal(t, "ok", reply["status"])
+	assert.Equal(t, 1.0, reply["execution_count"])
+
+	var output string
+	for !strings.Contains(output, "42") {
+		msg, err := iopub.Recv()
+		require.NoError(t, err)
+		stream, err := client.parseMessage(msg.Frames)
+		require.NoError(t, err)
+		var content struct{ Name, Text string }
+		require.NoError(t, json.Unmarshal(stream.content, &content))
+		assert.Equal(t, "stdout", content.Name)
+		output += content.Text
+	}
+	assert.Equal(t, "40\n42\n", output)
+
+	reply = request("execute_request", map[string]any{"code": "y"})
+	assert.Equal(t, "error", reply["status"])
+	assert.Equal(t, "In [2]:1: <command failed>", reply["evalue"])
+
+	reply = request("is_complete_request", map[string]any{"code": "func f() {"})
+	assert.Equal(t, "incomplete", reply["status"])
+
+	reply = request("complete_request", map[string]any{"code": "x + 1\n:cl", "cursor_pos": 9})
+	assert.Equal(t, []any{"clear"}, reply["matches"])
+	assert.Equal(t, 7.0, reply["cursor_start"])
+	assert.Equal(t, 9.0, reply["cursor_end"])
+
+	reply = request("inspect_request", map[string]any{"code": "x + 1", "cursor_pos": 0})
+	assert.Equal(t, true, reply["found"])
+	assert.Contains(t, reply["data"], "text/plain")
+
+	// the interrupt is sent to the control socket until the execution stops
+	control := zmq4.NewDealer(ctx)
+	defer control.Close()
+	require.NoError(t, control.Dial(info.endpoint(info.ControlPort)))
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(500 * time.Millisecond):
+			}
+			req := &kernelMessage{header: kernelHeader{MsgType: "interrupt_request"}}
+			if control.Send(zmq4.NewMsgFrom(client.frames(nil, "interrupt_request", req, map[string]any{})...)) != nil {
+				return
+			}
+			if _, err := control.Recv(); err != nil {
+				return
+			}
+		}
+	}()
+	start := time.Now()
+	reply = request("execute_request", map[string]any{"code": ":import time\ntime.Sleep(time.Minute)\nx"})
+	close(stop)
+	assert.Equal(t, "error", reply["status"])
+	assert.Equal(t, "Interrupted", reply["ename"])
+	assert.Less(t, time.Since(start), 30*time.Second)
+
+	// the frontends send SIGINT in the default interrupt mode
+	if runtime.GOOS != "windows" {
+		process, err := os.FindProcess(os.Getpid())
+		require.NoError(t, err)
+		stop := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-stop:
+					return
+				case <-time.After(500 * time.Millisecond):
+				}
+				if process.Signal(os.Interrupt) != nil {
+					return
+				}
+			}
+		}()
+		start := time.Now()
+		reply = request("execute_request", map[string]any{"code": "time.Sleep(time.Minute)\nx"})
+		close(stop)
+		assert.Equal(t, "error", reply["status"])
+		assert.Equal(t, "Interrupted", reply["ename"])
+		assert.Less(t, time.Since(start), 30*time.Second)
+	}
+
+	reply = request("shutdown_request", map[string]any{"restart": false})
+	assert.Equal(t, "ok", reply["status"])
+	require.NoError(t, <-done)
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestExprAt(t *testing.T) {
+	testCases := []struct {
+		src  string
+		pos  int
+		expr string
+	}{
+		{"json.Marshal(x)", 0, "json.Marshal"},
+		{"json.Marshal(x)", 7, "json.Marshal"},
+		{"json.Marshal(x)", 13, "x"},
+		{"x + 1", 2, ""},
+		{"f(é.Name)", 4, "é.Name"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expr, exprAt(tc.src, tc.pos), "%q at %d", tc.src, tc.pos)
+	}
+}
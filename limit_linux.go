@@ -0,0 +1,43 @@
+package gore
+
+import (
+	"fmt"
+	"time"
+)
+
+const limitsSupported = true
+
+// limitSource is the package which sets the resource limits of the program
+// from the environment variables. The package is imported by the program, so
+// the limits are set before the variables of the session are initialized. The
+// memory is limited by RLIMIT_DATA, since the Go runtime reserves large
+// address space.
+const limitSource = `package gore_limit
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+func init() {
+	for name, resource := range map[string]int{
+		"GORE_MEMLIMIT": syscall.RLIMIT_DATA,
+		"GORE_CPULIMIT": syscall.RLIMIT_CPU,
+	} {
+		if v, err := strconv.ParseUint(os.Getenv(name), 10, 64); err == nil && v > 0 {
+			if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: v, Max: v}); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+`
+
+// limitEnv returns the environment variables of the limits for limitSource.
+func limitEnv(memory uint64, cpu time.Duration) []string {
+	return []string{
+		fmt.Sprintf("GORE_MEMLIMIT=%d", memory),
+		fmt.Sprintf("GORE_CPULIMIT=%d", (cpu+time.Second-1)/time.Second),
+	}
+}
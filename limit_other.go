@@ -0,0 +1,13 @@
+//go:build !linux
+
+package gore
+
+import "time"
+
+const limitsSupported = false
+
+const limitSource = ""
+
+func limitEnv(uint64, time.Duration) []string {
+	return nil
+}
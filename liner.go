@@ -21,12 +21,13 @@ type contLiner struct {
 	*liner.State
 	buffer string
 	depth  int
+	prompt string
 }
 
 func newContLiner() *contLiner {
 	rl := liner.NewLiner()
 	rl.SetCtrlCAborts(true)
-	return &contLiner{State: rl}
+	return &contLiner{State: rl, prompt: promptDefault}
 }
 
 func (cl *contLiner) promptString() string {
@@ -34,7 +35,7 @@ func (cl *contLiner) promptString() string {
 		return promptContinue + strings.Repeat(indent, cl.depth)
 	}
 
-	return promptDefault
+	return cl.prompt
 }
 
 func (cl *contLiner) Prompt() (string, error) {
@@ -100,7 +101,12 @@ func (cl *contLiner) Reindent() error {
 }
 
 func (cl *contLiner) countDepth() int {
-	reader := bytes.NewBufferString(cl.buffer)
+	return countDepth(cl.buffer)
+}
+
+// countDepth returns the depth of braces and parentheses at the end of src.
+func countDepth(src string) int {
+	reader := bytes.NewBufferString(src)
 	sc := new(scanner.Scanner)
 	sc.Init(reader)
 	sc.Error = func(_ *scanner.Scanner, msg string) {
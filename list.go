@@ -0,0 +1,233 @@
+package gore
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// sessionNodes returns the declarations and the statements in the session,
+// which are numbered by :list.
+func (s *Session) sessionNodes() []ast.Node {
+	var nodes []ast.Node
+	for _, decl := range s.file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			if decl.Tok == token.IMPORT {
+				continue
+			}
+		case *ast.FuncDecl:
+			if decl.Recv == nil && (decl.Name.Name == "main" || strings.HasPrefix(decl.Name.Name, "__gore_")) {
+				continue
+			}
+		}
+		nodes = append(nodes, decl)
+	}
+	for _, stmt := range s.mainBody.List {
+		nodes = append(nodes, stmt)
+	}
+	return nodes
+}
+
+func actionList(s *Session, _ string) error {
+	s.clearQuickFix()
+	normalizeNodePos(s.mainFunc())
+
+	nodes := s.sessionNodes()
+	width := len(strconv.Itoa(len(nodes)))
+	for i, node := range nodes {
+		// the declarations shadowed by the later inputs are shown as declared
+		lines := strings.Split(unshadow(showNode(s.fset, node)), "\n")
+		fmt.Fprintf(s.stdout, "%*d: %s\n", width, i+1, lines[0])
+		for _, line := range lines[1:] {
+			fmt.Fprintf(s.stdout, "%*s  %s\n", width, "", line)
+		}
+	}
+	return nil
+}
+
+func actionDrop(s *Session, arg string) error {
+	if arg == "" {
+		return errors.New("argument is required")
+	}
+
+	s.clearQuickFix()
+
+	snap, err := s.snapshot()
+	if err != nil {
+		return err
+	}
+
+	n, err := strconv.Atoi(arg)
+	if err == nil {
+		err = s.dropNode(n)
+	} else {
+		err = s.dropName(arg)
+	}
+	if err != nil {
+		return err
+	}
+	s.checkPrinter()
+
+	// the statements using the dropped one cannot be fixed
+	s.doQuickFix()
+	if _, err := s.types.Check("_tmp", s.fset, append(s.extraFiles, s.file), nil); err != nil {
+		msg := err.Error()
+		if err, ok := err.(types.Error); ok {
+			msg = err.Msg
+		}
+		if err := s.restoreSnapshot(snap); err != nil {
+			return err
+		}
+		return fmt.Errorf("cannot drop %s: %s", arg, msg)
+	}
+	s.clearQuickFix()
+
+	// the saved variables may not be in the code anymore
+	return s.resetState()
+}
+
+// dropNode removes the n-th node listed by :list.
+func (s *Session) dropNode(n int) error {
+	nodes := s.sessionNodes()
+	if n < 1 || n > len(nodes) {
+		return fmt.Errorf("invalid number: %d", n)
+	}
+	switch node := nodes[n-1].(type) {
+	case ast.Decl:
+		s.file.Decls = slices.DeleteFunc(s.file.Decls, func(decl ast.Decl) bool { return decl == node })
+	case ast.Stmt:
+		s.mainBody.List = slices.DeleteFunc(s.mainBody.List, func(stmt ast.Stmt) bool { return stmt == node })
+	}
+	return nil
+}
+
+// dropName removes the declaration of the function, the method (T.m), the
+// type with its methods, or the variable or constant. The other names declared
+// along with it are kept.
+func (s *Session) dropName(name string) error {
+	if name == "main" || strings.HasPrefix(name, "__gore_") {
+		return fmt.Errorf("not found: %s", name)
+	}
+
+	var found, isType bool
+	for _, decl := range s.file.Decls {
+		if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.TYPE {
+			for _, spec := range decl.Specs {
+				isType = isType || spec.(*ast.TypeSpec).Name.Name == name
+			}
+		}
+	}
+	s.file.Decls = slices.DeleteFunc(s.file.Decls, func(decl ast.Decl) bool {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			key := funcKey(decl)
+			if key == name || isType && key == name+"."+decl.Name.Name {
+				found = true
+				return true
+			}
+		case *ast.GenDecl:
+			if decl.Tok == token.IMPORT {
+				return false
+			}
+			drop, ok := s.dropDeclared(&ast.DeclStmt{Decl: decl}, name)
+			found = found || ok
+			return drop
+		}
+		return false
+	})
+	if found {
+		return nil
+	}
+
+	s.mainBody.List = slices.DeleteFunc(s.mainBody.List, func(stmt ast.Stmt) bool {
+		drop, ok := s.dropDeclared(stmt, name)
+		found = found || ok
+		return drop
+	})
+	if !found {
+		return fmt.Errorf("not found: %s", name)
+	}
+	return nil
+}
+
+// dropDeclared renames the name declared by the statement to _, and reports
+// whether the statement declares nothing else and can be removed.
+func (s *Session) dropDeclared(stmt ast.Stmt, name string) (drop, found bool) {
+	switch stmt := stmt.(type) {
+	case *ast.AssignStmt:
+		if stmt.Tok != token.DEFINE || !renameBlank(stmt.Lhs, name) {
+			return false, false
+		}
+		if !allBlank(stmt.Lhs) {
+			return false, true
+		}
+		if s.allPure(stmt.Rhs) {
+			return true, true
+		}
+		// the values may have side effects
+		stmt.Tok = token.ASSIGN
+		return false, true
+
+	case *ast.DeclStmt:
+		decl, ok := stmt.Decl.(*ast.GenDecl)
+		if !ok {
+			return false, false
+		}
+		decl.Specs = slices.DeleteFunc(decl.Specs, func(spec ast.Spec) bool {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				if spec.Name.Name == name {
+					found = true
+					return true
+				}
+			case *ast.ValueSpec:
+				exprs := make([]ast.Expr, len(spec.Names))
+				for i, ident := range spec.Names {
+					exprs[i] = ident
+				}
+				if renameBlank(exprs, name) {
+					found = true
+					return allBlank(exprs) && s.allPure(spec.Values)
+				}
+			}
+			return false
+		})
+		return found && len(decl.Specs) == 0, found
+	}
+	return false, false
+}
+
+// renameBlank renames the identifiers of the name to _.
+func renameBlank(exprs []ast.Expr, name string) bool {
+	var renamed bool
+	for _, expr := range exprs {
+		if isNamedIdent(expr, name) {
+			expr.(*ast.Ident).Name, renamed = "_", true
+		}
+	}
+	return renamed
+}
+
+func allBlank(exprs []ast.Expr) bool {
+	for _, expr := range exprs {
+		if !isNamedIdent(expr, "_") {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Session) allPure(exprs []ast.Expr) bool {
+	for _, expr := range exprs {
+		if !s.isPureExpr(expr) {
+			return false
+		}
+	}
+	return true
+}
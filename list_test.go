@@ -0,0 +1,59 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_ListDrop(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		"func f() int {\n\treturn 1\n}",
+		"x, y := 1, 2",
+		"x++",
+		"for i := range 3 {\n\ty += i\n}",
+		":list",
+		":drop x",
+		":drop 3",
+		":drop x",
+		":drop f",
+		":list",
+		"y",
+		":drop",
+		":drop z",
+		":drop 10",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `1
+2
+1: func f() int {
+   	return 1
+   }
+2: x, y := 1, 2
+3: x++
+4: for i := range 3 {
+   	y += i
+   }
+1: _, y := 1, 2
+2: for i := range 3 {
+   	y += i
+   }
+5
+`, stdout.String())
+	assert.Equal(t, `drop: cannot drop x: undefined: x
+drop: argument is required
+drop: not found: z
+drop: invalid number: 10
+`, stderr.String())
+}
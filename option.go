@@ -1,6 +1,9 @@
 package gore
 
-import "io"
+import (
+	"io"
+	"time"
+)
 
 // Option for Gore
 type Option func(*Gore)
@@ -12,6 +15,27 @@ func AutoImport(autoImport bool) Option {
 	}
 }
 
+// Printer option
+func Printer(printer string) Option {
+	return func(g *Gore) {
+		g.printer = printer
+	}
+}
+
+// ShowType option
+func ShowType(showType bool) Option {
+	return func(g *Gore) {
+		g.showType = showType
+	}
+}
+
+// Persist option
+func Persist(persist bool) Option {
+	return func(g *Gore) {
+		g.persist = persist
+	}
+}
+
 // ExtFiles option
 func ExtFiles(extFiles string) Option {
 	return func(g *Gore) {
@@ -26,6 +50,78 @@ func PackageName(packageName string) Option {
 	}
 }
 
+// LoadFile option
+func LoadFile(loadFile string) Option {
+	return func(g *Gore) {
+		g.loadFile = loadFile
+	}
+}
+
+// Code option
+func Code(code string) Option {
+	return func(g *Gore) {
+		g.code = code
+	}
+}
+
+// ScriptFile option
+func ScriptFile(scriptFile string) Option {
+	return func(g *Gore) {
+		g.scriptFile = scriptFile
+	}
+}
+
+// TestFile option
+func TestFile(testFile string) Option {
+	return func(g *Gore) {
+		g.testFile = testFile
+	}
+}
+
+// KernelFile option
+func KernelFile(kernelFile string) Option {
+	return func(g *Gore) {
+		g.kernelFile = kernelFile
+	}
+}
+
+// Serve option, serves the session on the unix domain socket at addr, or on
+// the standard input and output if addr is empty.
+func Serve(addr string) Option {
+	return func(g *Gore) {
+		g.serve = true
+		g.serveAddr = addr
+	}
+}
+
+// Timeout option
+func Timeout(timeout time.Duration) Option {
+	return func(g *Gore) {
+		g.timeout = timeout
+	}
+}
+
+// Wait option, the duration to wait for the goroutines after the statements
+func Wait(wait time.Duration) Option {
+	return func(g *Gore) {
+		g.wait = wait
+	}
+}
+
+// MemoryLimit option, the size in bytes with an optional unit like 512M
+func MemoryLimit(memoryLimit string) Option {
+	return func(g *Gore) {
+		g.memoryLimit = memoryLimit
+	}
+}
+
+// CPULimit option
+func CPULimit(cpuLimit time.Duration) Option {
+	return func(g *Gore) {
+		g.cpuLimit = cpuLimit
+	}
+}
+
 // OutWriter option
 func OutWriter(outWriter io.Writer) Option {
 	return func(g *Gore) {
@@ -0,0 +1,728 @@
+package gore
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// In the persistent mode, the session program saves the values of the
+// variables in main and at the package level at the end of each run, and the
+// next run restores them instead of executing the statements of the previous
+// inputs again. When some variable cannot be restored (say, it holds a
+// channel), all the statements are executed as usual.
+
+const (
+	loaderName      = "__gore_load"
+	saverName       = "__gore_save"
+	globalSaverName = "__gore_save_globals"
+	committerName   = "__gore_commit"
+)
+
+const persistSourceTemplate = `package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"reflect"
+)
+
+var __gore_state, __gore_saved = __gore_read(), map[string][]byte{}
+
+func __gore_read() map[string][]byte {
+	state := map[string][]byte{}
+	if f, err := os.Open(%[1]q); err == nil {
+		defer f.Close()
+		_ = gob.NewDecoder(f).Decode(&state)
+	}
+	return state
+}
+
+func ` + loaderName + `(name string, v any) {
+	b, ok := __gore_state[name]
+	if !ok {
+		return
+	}
+	// the variables at the package level have the initial values
+	reflect.ValueOf(v).Elem().SetZero()
+	if len(b) > 0 {
+		if err := gob.NewDecoder(bytes.NewReader(b)).Decode(v); err != nil {
+			panic("gore: cannot restore " + name + ": " + err.Error())
+		}
+	}
+}
+
+func ` + saverName + `(name string, v any) {
+	if reflect.ValueOf(v).Elem().IsZero() {
+		__gore_saved[name] = nil
+		return
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err == nil {
+		__gore_saved[name] = buf.Bytes()
+	}
+}
+
+func ` + committerName + `() {
+	f, err := os.Create(%[2]q)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_ = gob.NewEncoder(f).Encode(__gore_saved)
+}
+`
+
+func (s *Session) statePath() string {
+	return filepath.Join(s.tempDir, "gore_state.gob")
+}
+
+// resetState discards the saved state so that the next run executes all the
+// statements.
+func (s *Session) resetState() error {
+	s.stateReady = false
+	for _, path := range []string{s.statePath(), s.statePath() + ".new"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+var errCannotRestore = errors.New("cannot restore the variables without running the previous inputs")
+
+// runPersistent runs the session restoring the variables saved by the
+// previous run, then saves the variables for the next run.
+func (s *Session) runPersistent(ctx context.Context) error {
+	defer s.useWaiter()()
+	restore := s.useLimitedPrinter()
+	source, globals, err := s.persistentSource()
+	restore()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(s.tempFilePath, []byte(source), 0o644); err != nil {
+		return err
+	}
+
+	persistFilePath := filepath.Join(s.tempDir, "gore_persist.go")
+	persistSource := fmt.Sprintf(persistSourceTemplate, s.statePath(), s.statePath()+".new")
+	if err := os.WriteFile(persistFilePath, []byte(persistSource), 0o644); err != nil {
+		return err
+	}
+
+	if err := s.goRun(ctx, append(s.extraFilePaths, s.tempFilePath, persistFilePath)); err != nil {
+		os.Remove(s.statePath() + ".new")
+		return err
+	}
+
+	// the program may exit before saving the state (e.g. os.Exit)
+	if err := os.Rename(s.statePath()+".new", s.statePath()); err != nil {
+		debugf("state not saved: %s", err)
+		s.stateReady = false
+	} else {
+		s.stateReady, s.stateGlobals = true, globals
+	}
+
+	return nil
+}
+
+// persistentSource returns the source code of the session, where the
+// statements of the previous inputs are replaced with restoration of the
+// variables if possible, and the names of the variables at the package level.
+func (s *Session) persistentSource() (string, map[string]bool, error) {
+	source, err := s.source(false)
+	if err != nil {
+		return "", nil, err
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "gore_session.go", source, parser.Mode(0))
+	if err != nil {
+		return "", nil, err
+	}
+
+	scope := s.typeInfo.Scopes[s.mainFunc().Type]
+	mainObj := s.typeInfo.Defs[s.mainFunc().Name]
+	if scope == nil || mainObj == nil {
+		// the source does not compile anyway
+		return source, nil, nil
+	}
+
+	p := &persistPlan{session: s, fset: fset, file: file, pkg: mainObj.Pkg(), scope: scope}
+	body := file.Scope.Lookup("main").Decl.(*ast.FuncDecl).Body
+	if stmts, ok := p.restore(body.List); ok {
+		body.List = stmts
+		p.blankUnusedImports()
+	} else if s.restoreOnly {
+		return "", nil, errCannotRestore
+	} else {
+		debugf("persist :: cannot restore the state, running all the statements")
+	}
+	body.List = append(body.List, p.save()...)
+
+	globals := map[string]bool{}
+	for _, v := range p.globals() {
+		globals[v.Name()] = true
+	}
+	return showNode(fset, file), globals, nil
+}
+
+type persistPlan struct {
+	session *Session
+	fset    *token.FileSet
+	file    *ast.File
+	pkg     *types.Package
+	scope   *types.Scope
+}
+
+// blankUnusedImports renames the imports which are no longer used after the
+// statements are replaced to the blank identifier.
+func (p *persistPlan) blankUnusedImports() {
+	used := map[string]bool{}
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	names := map[string]string{}
+	for _, pkg := range p.pkg.Imports() {
+		names[pkg.Path()] = pkg.Name()
+	}
+	for _, imp := range p.file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name := names[path]
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if name != "" && name != "_" && name != "." && !used[name] {
+			imp.Name = ast.NewIdent("_")
+		}
+	}
+}
+
+// restore returns the statements of main in which the executed statements
+// are replaced with restoration of the variables they declare. The list of
+// statements is of the reparsed file, and corresponds to mainBody one by one.
+func (p *persistPlan) restore(list []ast.Stmt) ([]ast.Stmt, bool) {
+	s := p.session
+	if !s.stateReady || len(list) != len(s.mainBody.List) {
+		return nil, false
+	}
+
+	saved, err := readStateNames(s.statePath())
+	if err != nil {
+		debugf("persist :: %s", err)
+		return nil, false
+	}
+
+	// the variables at the package level may be changed by any statement, so
+	// the saved values are loaded over the initial values
+	var loads []ast.Stmt
+	var restored []types.Type
+	for _, v := range p.globals() {
+		name := v.Name()
+		if !s.stateGlobals[name] {
+			// the renamed ones are redefined by the current input
+			if unshadow(name) == name {
+				continue
+			}
+		} else if saved[globalKey(name)] && p.persistable(v.Type(), map[types.Type]bool{}) {
+			loads = append(loads, persistCall(loaderName, globalKey(name), name))
+			restored = append(restored, v.Type())
+			continue
+		}
+		debugf("persist :: cannot restore the package level variable %s", name)
+		return nil, false
+	}
+
+	var stmts, restores []ast.Stmt
+	replayed, assigned := map[string]bool{}, map[string]bool{}
+	for i, stmt := range s.mainBody.List {
+		if s.inputOf(i) >= s.inputCount {
+			stmts = append(stmts, list[i])
+			continue
+		}
+
+		if names, ok := p.replayable(stmt); ok {
+			stmts = append(stmts, list[i])
+			for _, name := range names {
+				if name != "_" {
+					// the statements using it may have been dropped
+					replayed[name] = true
+					stmts = append(stmts, &ast.AssignStmt{
+						Lhs: []ast.Expr{ast.NewIdent("_")},
+						Tok: token.ASSIGN,
+						Rhs: []ast.Expr{ast.NewIdent(name)},
+					})
+				}
+			}
+			continue
+		}
+
+		for _, name := range assignedNames(stmt) {
+			assigned[name] = true
+		}
+
+		for _, name := range declaredNames(stmt) {
+			v, ok := p.scope.Lookup(name).(*types.Var)
+			if !ok || !saved[name] || !p.persistable(v.Type(), map[types.Type]bool{}) {
+				debugf("persist :: cannot restore %s", name)
+				return nil, false
+			}
+			typ, err := p.typeExpr(v.Type())
+			if err != nil {
+				debugf("persist :: cannot restore %s: %s", name, err)
+				return nil, false
+			}
+			restores = append(restores,
+				&ast.DeclStmt{Decl: &ast.GenDecl{
+					Tok:   token.VAR,
+					Specs: []ast.Spec{&ast.ValueSpec{Names: []*ast.Ident{ast.NewIdent(name)}, Type: typ}},
+				}},
+				persistCall(loaderName, name, name),
+			)
+			restored = append(restored, v.Type())
+		}
+	}
+
+	// the variables are saved separately, so the memory shared by them would
+	// be restored as copies
+	if mayShare(restored) {
+		debugf("persist :: cannot restore the variables which may share memory")
+		return nil, false
+	}
+
+	for name := range replayed {
+		if assigned[name] {
+			debugf("persist :: cannot replay %s", name)
+			return nil, false
+		}
+	}
+
+	return append(loads, append(restores, stmts...)...), true
+}
+
+// save returns the statements to save the variables of main, and adds the
+// function to save the variables at the package level, which may be shadowed
+// in main.
+func (p *persistPlan) save() []ast.Stmt {
+	var stmts, globals []ast.Stmt
+	for _, name := range p.scope.Names() {
+		if v, ok := p.scope.Lookup(name).(*types.Var); ok && name != "_" &&
+			p.persistable(v.Type(), map[types.Type]bool{}) {
+			stmts = append(stmts, persistCall(saverName, name, name))
+		}
+	}
+	for _, v := range p.globals() {
+		if p.persistable(v.Type(), map[types.Type]bool{}) {
+			globals = append(globals, persistCall(saverName, globalKey(v.Name()), v.Name()))
+		}
+	}
+	p.file.Decls = append(p.file.Decls, &ast.FuncDecl{
+		Name: ast.NewIdent(globalSaverName),
+		Type: &ast.FuncType{Params: &ast.FieldList{}},
+		Body: &ast.BlockStmt{List: globals},
+	})
+	return append(stmts,
+		&ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent(globalSaverName)}},
+		&ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent(committerName)}},
+	)
+}
+
+// globals returns the variables at the package level declared in the session.
+func (p *persistPlan) globals() []*types.Var {
+	var vars []*types.Var
+	for _, name := range p.pkg.Scope().Names() {
+		if v, ok := p.pkg.Scope().Lookup(name).(*types.Var); ok && p.session.inSession(v) {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+// globalKey returns the name of the variable at the package level in the
+// state, which does not conflict with the ones in main.
+func globalKey(name string) string {
+	return "main." + name
+}
+
+func persistCall(fun, key, name string) ast.Stmt {
+	return &ast.ExprStmt{
+		X: &ast.CallExpr{
+			Fun: ast.NewIdent(fun),
+			Args: []ast.Expr{
+				&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(key)},
+				&ast.UnaryExpr{Op: token.AND, X: ast.NewIdent(name)},
+			},
+		},
+	}
+}
+
+// replayable reports whether the statement can be executed again without
+// side effects instead of restoring the variables, and returns the names of
+// the variables it assigns. This is the case of function literals, which
+// cannot be saved.
+func (p *persistPlan) replayable(stmt ast.Stmt) ([]string, bool) {
+	s := p.session
+	switch stmt := stmt.(type) {
+	case *ast.DeclStmt:
+		decl, ok := stmt.Decl.(*ast.GenDecl)
+		if !ok {
+			return nil, false
+		}
+		if decl.Tok == token.CONST {
+			return nil, true
+		}
+		var names []string
+		for _, spec := range decl.Specs {
+			spec, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				return nil, false
+			}
+			for _, value := range spec.Values {
+				if !s.isPureExpr(value) {
+					return nil, false
+				}
+			}
+			for _, name := range spec.Names {
+				names = append(names, name.Name)
+			}
+		}
+		return names, p.allFuncs(names)
+	case *ast.AssignStmt:
+		if stmt.Tok != token.DEFINE && stmt.Tok != token.ASSIGN {
+			return nil, false
+		}
+		var names []string
+		for _, lhs := range stmt.Lhs {
+			ident, ok := lhs.(*ast.Ident)
+			if !ok {
+				return nil, false
+			}
+			names = append(names, ident.Name)
+		}
+		for _, rhs := range stmt.Rhs {
+			if !s.isPureExpr(rhs) {
+				return nil, false
+			}
+		}
+		return names, p.allFuncs(names)
+	}
+	return nil, false
+}
+
+func (p *persistPlan) allFuncs(names []string) bool {
+	for _, name := range names {
+		if name == "_" {
+			continue
+		}
+		v, ok := p.scope.Lookup(name).(*types.Var)
+		if !ok {
+			return false
+		}
+		if _, ok := v.Type().Underlying().(*types.Signature); !ok {
+			return false
+		}
+	}
+	return len(names) > 0
+}
+
+// persistable reports whether the values of the type can be saved and
+// restored by encoding/gob without losing information.
+func (p *persistPlan) persistable(t types.Type, seen map[types.Type]bool) bool {
+	t = types.Unalias(t)
+	if seen[t] {
+		return true
+	}
+	seen[t] = true
+
+	if t, ok := t.(*types.Named); ok {
+		if obj := t.Obj(); obj.Pkg() != nil && obj.Pkg() != p.pkg && !obj.Exported() {
+			return false
+		}
+		if gobEncoded(t) {
+			return true
+		}
+	}
+
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		return t.Kind() != types.UnsafePointer && t.Info()&types.IsUntyped == 0
+	case *types.Pointer:
+		return p.persistable(t.Elem(), seen)
+	case *types.Slice:
+		return p.persistable(t.Elem(), seen)
+	case *types.Array:
+		return p.persistable(t.Elem(), seen)
+	case *types.Map:
+		return p.persistable(t.Key(), seen) && p.persistable(t.Elem(), seen)
+	case *types.Struct:
+		for field := range t.Fields() {
+			if !field.Exported() || !p.persistable(field.Type(), seen) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gobEncoded reports whether the type encodes its values by itself.
+func gobEncoded(t *types.Named) bool {
+	mset := types.NewMethodSet(types.NewPointer(t))
+	for _, name := range []string{"GobEncode", "MarshalBinary"} {
+		if mset.Lookup(t.Obj().Pkg(), name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// typeRef is a type which a value refers to.
+type typeRef struct {
+	typ  types.Type
+	kind refKind
+}
+
+type refKind int
+
+const (
+	refPointer refKind = iota // a value of the type
+	refSlice                  // the elements of an array of the type
+	refMap                    // a map of the type
+)
+
+// mayShare reports whether the values of the types may share memory, say, a
+// pointer to another variable, or slices of the same array.
+func mayShare(ts []types.Type) bool {
+	refs := make([][]typeRef, len(ts))
+	for i, t := range ts {
+		var self bool
+		if refs[i], self = referredTypes(t, map[types.Type]bool{}); self {
+			return true
+		}
+	}
+	for i := range ts {
+		for j := range ts {
+			if i == j {
+				continue
+			}
+			for _, r := range refs[i] {
+				if r.refersTo(ts[j], refs[j]) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// referredTypes returns the types which the values of the type refer to
+// through pointers, slices and maps. It also reports whether a value of the
+// type may share memory in itself, like the elements of a slice of pointers.
+func referredTypes(t types.Type, path map[types.Type]bool) ([]typeRef, bool) {
+	t = types.Unalias(t)
+	if path[t] {
+		// the values may be cyclic
+		return nil, true
+	}
+	path[t] = true
+	defer delete(path, t)
+
+	if t, ok := t.(*types.Named); ok && gobEncoded(t) {
+		return nil, false
+	}
+
+	var refs []typeRef
+	var self bool
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		refs, self = referredTypes(u.Elem(), path)
+		refs = append(refs, typeRef{u.Elem(), refPointer})
+	case *types.Slice:
+		refs, self = referredTypes(u.Elem(), path)
+		self = self || len(refs) > 0
+		refs = append(refs, typeRef{u.Elem(), refSlice})
+	case *types.Map:
+		var elems []typeRef
+		refs, self = referredTypes(u.Key(), path)
+		if !self {
+			elems, self = referredTypes(u.Elem(), path)
+		}
+		self = self || len(refs) > 0 || len(elems) > 0
+		refs = append(append(refs, elems...), typeRef{t, refMap})
+	case *types.Array:
+		refs, self = referredTypes(u.Elem(), path)
+		self = self || u.Len() > 1 && len(refs) > 0
+	case *types.Struct:
+		for field := range u.Fields() {
+			var fieldRefs []typeRef
+			if fieldRefs, self = referredTypes(field.Type(), path); self {
+				break
+			}
+			refs = append(refs, fieldRefs...)
+		}
+	}
+	if !self {
+		for i, r := range refs {
+			if r.refersTo(t, refs[:i]) {
+				return refs, true
+			}
+		}
+	}
+	return refs, self
+}
+
+// refersTo reports whether the reference may point to the memory of a value
+// of the type, or the memory which the references of the value point to.
+func (r typeRef) refersTo(t types.Type, refs []typeRef) bool {
+	if r.kind != refMap && containsType(t, r.typ, r.kind == refSlice) {
+		return true
+	}
+	return slices.ContainsFunc(refs, func(q typeRef) bool {
+		return (r.kind == refMap) == (q.kind == refMap) && types.Identical(r.typ, q.typ)
+	})
+}
+
+// containsType reports whether the values of the type hold a value of the
+// other type in themselves, not through references. If inArray is true, the
+// value should be an element of an array.
+func containsType(t, elem types.Type, inArray bool) bool {
+	if !inArray && types.Identical(t, elem) {
+		return true
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Array:
+		return containsType(u.Elem(), elem, false)
+	case *types.Struct:
+		for field := range u.Fields() {
+			if containsType(field.Type(), elem, inArray) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeExpr returns the expression of the type, adding imports of the
+// packages it refers to.
+func (p *persistPlan) typeExpr(t types.Type) (ast.Expr, error) {
+	var err error
+	str := types.TypeString(t, func(pkg *types.Package) string {
+		if pkg == p.pkg {
+			return ""
+		}
+		for _, imp := range p.file.Imports {
+			if path, _ := strconv.Unquote(imp.Path.Value); path == pkg.Path() {
+				if imp.Name == nil {
+					return pkg.Name()
+				}
+				switch imp.Name.Name {
+				case "_":
+					imp.Name = nil
+					return pkg.Name()
+				case ".":
+					err = fmt.Errorf("dot import of %s", pkg.Path())
+				}
+				return imp.Name.Name
+			}
+		}
+		astutil.AddImport(p.fset, p.file, pkg.Path())
+		return pkg.Name()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parser.ParseExpr(str)
+}
+
+// declaredNames returns the names of variables declared by the statement.
+func declaredNames(stmt ast.Stmt) []string {
+	var names []string
+	switch stmt := stmt.(type) {
+	case *ast.DeclStmt:
+		if decl, ok := stmt.Decl.(*ast.GenDecl); ok && decl.Tok == token.VAR {
+			for _, spec := range decl.Specs {
+				if spec, ok := spec.(*ast.ValueSpec); ok {
+					for _, name := range spec.Names {
+						names = append(names, name.Name)
+					}
+				}
+			}
+		}
+	case *ast.AssignStmt:
+		if stmt.Tok == token.DEFINE {
+			for _, lhs := range stmt.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok {
+					names = append(names, ident.Name)
+				}
+			}
+		}
+	}
+	return slices.DeleteFunc(names, func(name string) bool { return name == "_" })
+}
+
+// assignedNames returns the names of identifiers possibly assigned in the
+// statement, including the nested ones.
+func assignedNames(stmt ast.Stmt) []string {
+	var names []string
+	ast.Inspect(stmt, func(node ast.Node) bool {
+		var exprs []ast.Expr
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			exprs = node.Lhs
+		case *ast.IncDecStmt:
+			exprs = []ast.Expr{node.X}
+		case *ast.RangeStmt:
+			exprs = []ast.Expr{node.Key, node.Value}
+		case *ast.UnaryExpr:
+			if node.Op == token.AND {
+				exprs = []ast.Expr{node.X}
+			}
+		}
+		for _, expr := range exprs {
+			if ident, ok := expr.(*ast.Ident); ok {
+				names = append(names, ident.Name)
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// readStateNames returns the names of variables saved in the state file.
+func readStateNames(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var state map[string][]byte
+	if err := gob.NewDecoder(f).Decode(&state); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(state))
+	for name := range state {
+		names[name] = true
+	}
+	return names, nil
+}
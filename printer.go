@@ -0,0 +1,239 @@
+package gore
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"path"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// valuePrinter prints the values of the expressions in the session.
+type valuePrinter struct {
+	name     string
+	imports  []string
+	module   pathVersion // the module of the printer not required by default
+	code     string      // the statements printing x
+	wcode    string      // the statements printing x to w
+	document string
+}
+
+// printers is a list of printers which can be selected by :printer command
+var printers = []valuePrinter{
+	{
+		name: "pp", imports: []string{"github.com/k0kubun/pp/v3"},
+		code: `pp.Println(x)`, wcode: `pp.Fprintln(w, x)`, document: "pretty print with colors",
+	},
+	{
+		name: "%#v", imports: []string{"fmt"},
+		code: `fmt.Printf("%#v\n", x)`, wcode: `fmt.Fprintf(w, "%#v\n", x)`, document: "Go-syntax representation",
+	},
+	{
+		name: "%+v", imports: []string{"fmt"},
+		code: `fmt.Printf("%+v\n", x)`, wcode: `fmt.Fprintf(w, "%+v\n", x)`, document: "default format with field names",
+	},
+	{
+		name: "%v", imports: []string{"fmt"},
+		code: `fmt.Printf("%v\n", x)`, wcode: `fmt.Fprintf(w, "%v\n", x)`, document: "default format",
+	},
+	{
+		name: "json", imports: []string{"encoding/json", "fmt"},
+		code: `if b, err := json.MarshalIndent(x, "", "  "); err != nil {
+			fmt.Printf("%#v (%s)\n", x, err)
+		} else {
+			fmt.Printf("%s\n", b)
+		}`,
+		wcode: `if b, err := json.MarshalIndent(x, "", "  "); err != nil {
+			fmt.Fprintf(w, "%#v (%s)\n", x, err)
+		} else {
+			fmt.Fprintf(w, "%s\n", b)
+		}`,
+		document: "indented JSON",
+	},
+	{
+		name: "spew", imports: []string{"github.com/davecgh/go-spew/spew"},
+		module: pathVersion{"github.com/davecgh/go-spew", "v1.1.1"},
+		code:   `spew.Dump(x)`, wcode: `spew.Fdump(w, x)`, document: "dump with types and pointers",
+	},
+}
+
+func lookupPrinter(name string) (valuePrinter, bool) {
+	for _, p := range printers {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return valuePrinter{}, false
+}
+
+func (s *Session) printerName() string {
+	return s.printer.name
+}
+
+// setPrinter replaces the printer function of the session, which prints the
+// values of the expressions. The name is one of the printers, or a function
+// in the session which takes a value.
+func (s *Session) setPrinter(name string) error {
+	p, ok := lookupPrinter(name)
+	if !ok {
+		var err error
+		if p, err = s.userPrinter(name); err != nil {
+			return err
+		}
+	}
+
+	if len(p.imports) > 0 {
+		_, err := packages.Load(
+			&packages.Config{
+				Dir:        s.tempDir,
+				BuildFlags: []string{"-mod=mod"},
+			},
+			p.imports...,
+		)
+		if err != nil {
+			return fmt.Errorf("could not import %q: %w", p.imports, err)
+		}
+	}
+
+	src := fmt.Sprintf(initialSourceTemplate, "fmt", p.code)
+	f, err := parser.ParseFile(s.fset, "gore_printer.go", src, parser.Mode(0))
+	if err != nil {
+		return err
+	}
+	for _, decl := range f.Decls {
+		if decl, ok := decl.(*ast.FuncDecl); ok && decl.Name.Name == printerName {
+			s.replacePrinter(decl)
+		}
+	}
+
+	for _, path := range p.imports {
+		astutil.AddImport(s.fset, s.file, path)
+	}
+	for _, path := range s.printer.imports {
+		if !usesPackage(s.file, importName(path)) {
+			astutil.DeleteImport(s.fset, s.file, path)
+		}
+	}
+	s.printer = p
+	return nil
+}
+
+// userPrinter checks the function in the session to be used as a printer.
+func (s *Session) userPrinter(name string) (valuePrinter, error) {
+	expr, err := parser.ParseExpr(name)
+	if err != nil {
+		return valuePrinter{}, fmt.Errorf("unknown printer: %s", name)
+	}
+	switch expr := expr.(type) {
+	case *ast.Ident:
+	case *ast.SelectorExpr:
+		if _, ok := expr.X.(*ast.Ident); !ok {
+			return valuePrinter{}, fmt.Errorf("unknown printer: %s", name)
+		}
+	default:
+		return valuePrinter{}, fmt.Errorf("unknown printer: %s", name)
+	}
+
+	typ, err := s.exprType(name)
+	if err != nil {
+		return valuePrinter{}, fmt.Errorf("unknown printer: %s", name)
+	}
+	if !takesValue(typ) {
+		return valuePrinter{}, fmt.Errorf("%s is not a function taking a value: %s", name, typ)
+	}
+
+	return valuePrinter{name: name, code: name + "(x)", document: "user function"}, nil
+}
+
+// takesValue reports whether the type is a function taking a value of any type.
+func takesValue(typ types.Type) bool {
+	sig, ok := typ.Underlying().(*types.Signature)
+	if !ok || sig.Params().Len() != 1 {
+		return false
+	}
+	param := sig.Params().At(0).Type()
+	if sig.Variadic() {
+		param = param.(*types.Slice).Elem()
+	}
+	return types.AssignableTo(types.Universe.Lookup("any").Type(), param)
+}
+
+// checkPrinter falls back to the default printer when the function of the
+// user printer is dropped or redefined not to take a value.
+func (s *Session) checkPrinter() {
+	name := s.printer.name
+	if _, ok := lookupPrinter(name); ok || name == "" || strings.Contains(name, ".") {
+		return
+	}
+
+	conf := *s.types
+	conf.Error = func(error) {} // the uses of the function are broken
+	pkg, _ := conf.Check("_tmp", s.fset, append(s.extraFiles, s.file), nil)
+	if obj := pkg.Scope().Lookup(name); obj != nil && takesValue(obj.Type()) {
+		return
+	}
+
+	for _, pp := range printerPkgs {
+		if err := s.setPrinter(pp.name); err == nil {
+			fmt.Fprintf(s.stderr, "printer: %s is no longer a function taking a value, using %s\n", name, pp.name)
+			return
+		}
+	}
+}
+
+func (s *Session) replacePrinter(printer *ast.FuncDecl) {
+	for i, decl := range s.file.Decls {
+		if decl, ok := decl.(*ast.FuncDecl); ok && decl.Name.Name == printerName {
+			s.file.Decls[i] = printer
+			return
+		}
+	}
+}
+
+// usesPackage reports whether the file refers to the package of the name.
+func usesPackage(f *ast.File, name string) bool {
+	var used bool
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok && isNamedIdent(sel.X, name) {
+			used = true
+		}
+		return !used
+	})
+	return used
+}
+
+// importName returns the package name of the import path, which is the last
+// element of the path except for the major version suffix.
+func importName(p string) string {
+	dir, name := path.Split(p)
+	if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = path.Base(dir)
+	}
+	return name
+}
+
+func actionPrinter(s *Session, arg string) error {
+	if arg == "" {
+		fmt.Fprintln(s.stdout, s.printerName())
+		return nil
+	}
+	if strings.ContainsAny(arg, " \t") {
+		return errors.New("too many arguments")
+	}
+	return s.setPrinter(arg)
+}
+
+func completePrinter(_ *Session, prefix string) []string {
+	var result []string
+	for _, p := range printers {
+		if strings.HasPrefix(p.name, prefix) {
+			result = append(result, p.name)
+		}
+	}
+	return result
+}
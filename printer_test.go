@@ -0,0 +1,160 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_Printer(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		"type T struct { A int; B string }",
+		`x := T{1, "foo"}`,
+		":printer",
+		":printer %v",
+		"x",
+		":printer %+v",
+		"x",
+		":printer json",
+		"x",
+		"func show(x any) { fmt.Println(\"show:\", x) }",
+		":printer show",
+		"x",
+		":printer",
+		":printer foo",
+		":printer strings.Repeat",
+		":printer 1 2",
+		":undo",
+		":printer",
+		"x",
+		":set printer %v",
+		":clear",
+		":printer",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `main.T{A:1, B:"foo"}
+%#v
+{1 foo}
+{A:1 B:foo}
+{
+  "A": 1,
+  "B": "foo"
+}
+show: {1 foo}
+show
+json
+{
+  "A": 1,
+  "B": "foo"
+}
+%v
+`, stdout.String())
+	assert.Equal(t, `printer: unknown printer: foo
+printer: unknown printer: strings.Repeat
+printer: too many arguments
+`, stderr.String())
+}
+
+func TestSession_setPrinter(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	require.NoError(t, s.Eval(":import strings"))
+	require.NoError(t, s.Eval("func add(x, y int) int { return x + y }"))
+	assert.EqualError(t, s.setPrinter("strings.Repeat"), "strings.Repeat is not a function taking a value: func(s string, count int) string")
+	assert.EqualError(t, s.setPrinter("add"), "add is not a function taking a value: func(x int, y int) int")
+	assert.EqualError(t, s.setPrinter("strings.ToUpper"), "strings.ToUpper is not a function taking a value: func(s string) string")
+
+	require.NoError(t, s.setPrinter("json"))
+	require.NoError(t, s.setPrinter("%v"))
+	source, err := s.source(false)
+	require.NoError(t, err)
+	assert.NotContains(t, source, `"encoding/json"`)
+	assert.Contains(t, source, `"strings"`)
+}
+
+func TestAction_Printer_Removed(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		":import fmt",
+		`func show(x any) { fmt.Println("show:", x) }`,
+		":printer show",
+		"1",
+		":drop show",
+		":printer",
+		"2",
+		":undo",
+		":printer",
+		"3",
+		"var show = 3",
+		":printer",
+		"4",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `show: 1
+%#v
+2
+show
+show: 3
+3
+%#v
+4
+`, stdout.String())
+	assert.Equal(t, `printer: show is no longer a function taking a value, using %#v
+printer: show is no longer a function taking a value, using %#v
+`, stderr.String())
+}
+
+func TestAction_Printer_Once(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		":import fmt os",
+		`func show(x any) { fmt.Fprintln(os.Stderr, "show:", x); fmt.Println("=>", x) }`,
+		":printer show",
+		":set maxbytes 5",
+		"12345",
+		":full",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `=> 12
+... 4 more bytes of output (:full to show all)
+=> 12345
+`, stdout.String())
+	assert.Equal(t, "show: 12345\n", stderr.String())
+}
+
+func TestImportName(t *testing.T) {
+	assert.Equal(t, "fmt", importName("fmt"))
+	assert.Equal(t, "json", importName("encoding/json"))
+	assert.Equal(t, "pp", importName("github.com/k0kubun/pp/v3"))
+	assert.Equal(t, "spew", importName("github.com/davecgh/go-spew/spew"))
+}
@@ -0,0 +1,67 @@
+//go:build !windows
+
+package gore
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// setProcessGroup runs the command in a new process group, so that the
+// processes spawned by the command are killed together on cancellation.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// setForegroundProcessGroup runs the command in a new process group like
+// setProcessGroup. If the input is the terminal, the process group is put in
+// the foreground, so that the program can read the terminal and Ctrl-C
+// interrupts the processes spawned by it as well. The returned function takes
+// the terminal back after the command exits.
+func setForegroundProcessGroup(cmd *exec.Cmd, stdin io.Reader) func() {
+	setProcessGroup(cmd)
+	f, ok := stdin.(*os.File)
+	if !ok {
+		return func() {}
+	}
+	fd := int(f.Fd())
+	pgrp, err := foregroundProcessGroup(fd)
+	if err != nil || pgrp != syscall.Getpgrp() {
+		return func() {}
+	}
+	cmd.SysProcAttr.Foreground = true
+	cmd.SysProcAttr.Ctty = fd
+	return func() {
+		// the process in the background is stopped by SIGTTOU otherwise
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+		if err := setForeground(fd, pgrp); err != nil {
+			debugf("failed to take the terminal back: %s", err)
+		}
+	}
+}
+
+func foregroundProcessGroup(fd int) (int, error) {
+	var pgrp int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd),
+		uintptr(syscall.TIOCGPGRP), uintptr(unsafe.Pointer(&pgrp))); errno != 0 {
+		return 0, errno
+	}
+	return int(pgrp), nil
+}
+
+func setForeground(fd, pgrp int) error {
+	p := int32(pgrp)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd),
+		uintptr(syscall.TIOCSPGRP), uintptr(unsafe.Pointer(&p))); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package gore
+
+import (
+	"io"
+	"os/exec"
+)
+
+// setProcessGroup does nothing on Windows, where the command is killed alone
+// on cancellation.
+func setProcessGroup(*exec.Cmd) {}
+
+// setForegroundProcessGroup does nothing on Windows like setProcessGroup.
+func setForegroundProcessGroup(*exec.Cmd, io.Reader) func() {
+	return func() {}
+}
@@ -22,7 +22,10 @@ func (s *Session) doQuickFix() {
 L:
 	for range maxAttempts {
 		s.typeInfo = types.Info{
-			Types: make(map[ast.Expr]types.TypeAndValue),
+			Types:  make(map[ast.Expr]types.TypeAndValue),
+			Defs:   make(map[*ast.Ident]types.Object),
+			Uses:   make(map[*ast.Ident]types.Object),
+			Scopes: make(map[ast.Node]*types.Scope),
 		}
 
 		config := quickfix.Config{
@@ -50,6 +53,16 @@ L:
 				continue
 			}
 
+			// "cannot use _ as value or type":
+			//
+			// convert
+			//   _ + 1
+			// to
+			//   _3 + 1
+			if s.fixBlankResult(err) {
+				continue L
+			}
+
 			// "... used as value":
 			//
 			// convert
@@ -72,9 +85,12 @@ L:
 
 						exprs := printedExprs(stmt)
 
+						n := s.inputOf(i)
 						stmts := s.mainBody.List[0:i]
 						for _, expr := range exprs {
-							stmts = append(stmts, &ast.ExprStmt{X: expr})
+							stmt := &ast.ExprStmt{X: expr}
+							s.stmtInputs[stmt] = n
+							stmts = append(stmts, stmt)
 						}
 
 						s.mainBody.List = append(stmts, s.mainBody.List[i+1:]...)
@@ -121,6 +137,7 @@ func (s *Session) clearQuickFix() {
 			}
 
 			// convert possibly impure expressions to blank assignment
+			n := s.inputOf(i)
 			var trailing []ast.Stmt
 			s.mainBody.List, trailing = s.mainBody.List[0:i], s.mainBody.List[i+1:]
 			for _, expr := range exprs {
@@ -135,9 +152,11 @@ func (s *Session) clearQuickFix() {
 					} else {
 						lhs = []ast.Expr{ast.NewIdent("_")}
 					}
-					s.mainBody.List = append(s.mainBody.List, &ast.AssignStmt{
+					stmt := &ast.AssignStmt{
 						Lhs: lhs, Tok: token.ASSIGN, Rhs: []ast.Expr{expr},
-					})
+					}
+					s.stmtInputs[stmt] = n
+					s.mainBody.List = append(s.mainBody.List, stmt)
 				}
 			}
 
@@ -0,0 +1,142 @@
+package gore
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+)
+
+// redefine renames the variables, the constants and the types of the previous
+// inputs which the statement declares again, so that the statement shadows
+// them like a nested scope. The previous inputs keep using the renamed ones.
+// The names are looked up in the package scope if the statement is placed
+// there, otherwise in main.
+func (s *Session) redefine(stmt ast.Stmt, global bool) {
+	var names []string
+	switch stmt := stmt.(type) {
+	case *ast.AssignStmt:
+		if stmt.Tok == token.DEFINE {
+			names = declaredNames(stmt)
+		}
+	case *ast.DeclStmt:
+		decl, ok := stmt.Decl.(*ast.GenDecl)
+		if !ok {
+			return
+		}
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, spec.Name.Name)
+			case *ast.ValueSpec:
+				for _, name := range spec.Names {
+					if name.Name != "_" {
+						names = append(names, name.Name)
+					}
+				}
+			}
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+
+	pkg := s.checkSession()
+	if pkg == nil {
+		return
+	}
+	objs := map[types.Object]string{}
+
+	if global {
+		for _, name := range names {
+			if obj := pkg.Scope().Lookup(name); obj != nil && s.inSession(obj) {
+				objs[obj] = s.shadowedName(name)
+			}
+		}
+	} else if scope := s.typeInfo.Scopes[s.mainFunc().Type]; scope != nil {
+		var found []types.Object
+		for _, name := range names {
+			if obj := scope.Lookup(name); obj != nil {
+				found = append(found, obj)
+			}
+		}
+		// := redeclares the variables if it declares some new one
+		if _, ok := stmt.(*ast.AssignStmt); !ok || len(found) == len(names) {
+			for _, obj := range found {
+				objs[obj] = s.shadowedName(obj.Name())
+			}
+		}
+	}
+
+	// the values of the statement refer to the previous ones, unless they are
+	// declared in the values (e.g. the parameters of function literals)
+	renamed := map[string]string{}
+	for obj, name := range objs {
+		renamed[obj.Name()] = name
+	}
+	var inspect func(ast.Node) bool
+	inspect = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			ast.Inspect(n.X, inspect)
+			return false
+		case *ast.Ident:
+			if name, ok := renamed[n.Name]; ok && n.Obj == nil {
+				n.Name = name
+			}
+		}
+		return true
+	}
+	for _, value := range declaredValues(stmt) {
+		ast.Inspect(value, inspect)
+	}
+
+	ast.Inspect(s.file, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			obj := s.typeInfo.Defs[ident]
+			if obj == nil {
+				obj = s.typeInfo.Uses[ident]
+			}
+			if name, ok := objs[obj]; ok {
+				ident.Name = name
+			}
+		}
+		return true
+	})
+}
+
+// declaredValues returns the values of the variables and the constants which
+// the statement declares.
+func declaredValues(stmt ast.Stmt) []ast.Expr {
+	switch stmt := stmt.(type) {
+	case *ast.AssignStmt:
+		return stmt.Rhs
+	case *ast.DeclStmt:
+		var values []ast.Expr
+		if decl, ok := stmt.Decl.(*ast.GenDecl); ok {
+			for _, spec := range decl.Specs {
+				if spec, ok := spec.(*ast.ValueSpec); ok {
+					values = append(values, spec.Values...)
+				}
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// shadowedName returns the name of the variable or the type which is declared
+// again by the current input.
+func (s *Session) shadowedName(name string) string {
+	return fmt.Sprintf("__gore_%s_%d", name, s.inputCount)
+}
+
+// shadowedNamePattern matches the names renamed by shadowedName.
+var shadowedNamePattern = regexp.MustCompile(`__gore_(\w+)_\d+\b`)
+
+// unshadow replaces the renamed names in the text with the declared ones, so
+// that the users do not see the names made by redefine.
+func unshadow(text string) string {
+	return shadowedNamePattern.ReplaceAllString(text, "$1")
+}
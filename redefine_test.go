@@ -0,0 +1,151 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionEval_Redefine(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		"x := 1",
+		"y := x + 1",
+		`x := "a"`,
+		"x + x",
+		"y",
+		"var x float64 = 2.5",
+		"x",
+		"a, b := 1, 2",
+		"b, c := 3, 4",
+		"a + b + c",
+		"type T struct { a int }",
+		"t := T{1}",
+		"type T struct { b string }",
+		`T{"b"}`,
+		"t",
+		`"__gore_T_1"`, // not renamed by gore
+		":vars",
+		":undo",
+		"T{2}",
+		"a := a * 10",
+		"a",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `1
+2
+"a"
+"aa"
+2
+2.5
+2.5
+1
+2
+3
+4
+8
+main.T{a:1}
+main.T{b:"b"}
+main.T{a:1}
+"__gore_T_1"
+y int
+x float64
+a int
+b int
+c int
+t T
+main.T{a:2}
+10
+10
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestSessionEval_Redefine_Failed(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		"x := 1",
+		"x := undefinedFn()",
+		"x",
+		"type T struct { a int }",
+		"t := T{1}",
+		"type T struct { b string }",
+		":set showtype on",
+		"t",
+		":type t",
+		":list",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `1
+1
+main.T{a:1}
+main.T{a:1} : main.T
+T
+1: type T struct{ a int }
+2: type T struct{ b string }
+3: x := 1
+4: _3 := x
+5: t := T{1}
+6: _7 := t
+`, stdout.String())
+	assert.Equal(t, "undefined: undefinedFn\n", stderr.String())
+}
+
+func TestSessionEval_Methods(t *testing.T) {
+	t.Setenv("EDITOR", "sed -i s/Q2/Q3/")
+
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		"type P struct { x int }",
+		"type Q struct{}",
+		`func (p P) String() string { return "P" }`,
+		`func (q *Q) String() string { return "Q" }`,
+		`func String() string { return "f" }`,
+		`func (p P) String() string { return "P2" }`,
+		":funcs",
+		":drop Q.String",
+		":drop P",
+		":funcs",
+		":types",
+		`func (q Q) String() string { return "Q2" }`,
+		"Q{}.String() + String()",
+		":edit Q.String",
+		"Q{}.String()",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `P.String func() string
+Q.String func() string
+String   func() string
+String func() string
+Q struct{}
+"Q2f"
+"Q3"
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
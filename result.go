@@ -0,0 +1,105 @@
+package gore
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+var resultNamePattern = regexp.MustCompile(`^_[1-9][0-9]*$`)
+
+func resultName(n int) string {
+	return "_" + strconv.Itoa(n)
+}
+
+// bindResult binds the value of the expression printed for the current input
+// to the result variable of the input (_N), so that the later inputs can refer
+// to it. The values which cannot be assigned to a variable are just printed.
+func (s *Session) bindResult() bool {
+	for i, stmt := range s.mainBody.List {
+		exprs := printedExprs(stmt)
+		if len(exprs) != 1 || s.stmtInputs[stmt] != s.inputCount {
+			continue
+		}
+
+		tv, ok := s.typeInfo.Types[exprs[0]]
+		if !ok || !tv.IsValue() {
+			return false
+		}
+		if _, ok := tv.Type.(*types.Tuple); ok {
+			return false
+		}
+		if typ, ok := tv.Type.(*types.Basic); ok && (typ.Kind() == types.UntypedNil || typ.Kind() == types.Invalid) {
+			return false
+		}
+
+		name := resultName(s.inputCount)
+		assign := &ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent(name)},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{exprs[0]},
+		}
+		exprs[0] = ast.NewIdent(name)
+		s.mainBody.List = slices.Insert(s.mainBody.List, i, ast.Stmt(assign))
+		s.stmtInputs[assign] = s.inputCount
+		return true
+	}
+	return false
+}
+
+// isPureResult reports whether the current input just binds the result of a
+// pure expression.
+func (s *Session) isPureResult() bool {
+	for _, stmt := range s.mainBody.List {
+		if s.stmtInputs[stmt] != s.inputCount {
+			continue
+		}
+		assign, ok := stmt.(*ast.AssignStmt)
+		if !ok || len(assign.Lhs) != 1 || !isNamedIdent(assign.Lhs[0], resultName(s.inputCount)) ||
+			!s.isPureExpr(assign.Rhs[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// lastResult returns the name of the latest result variable in the session.
+func (s *Session) lastResult() string {
+	var name string
+	var last int
+	for _, stmt := range s.mainBody.List {
+		assign, ok := stmt.(*ast.AssignStmt)
+		if !ok || assign.Tok != token.DEFINE || len(assign.Lhs) != 1 {
+			continue
+		}
+		if ident, ok := assign.Lhs[0].(*ast.Ident); ok && resultNamePattern.MatchString(ident.Name) {
+			if n, _ := strconv.Atoi(ident.Name[1:]); n > last {
+				name, last = ident.Name, n
+			}
+		}
+	}
+	return name
+}
+
+// fixBlankResult replaces _ used as a value with the latest result variable.
+func (s *Session) fixBlankResult(err types.Error) bool {
+	if err.Msg != "cannot use _ as value or type" {
+		return false
+	}
+	name := s.lastResult()
+	if name == "" {
+		return false
+	}
+	// the input may not be positioned in the file, if it is not reparsed yet
+	var fixed bool
+	ast.Inspect(s.mainBody, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident.Pos() == err.Pos && ident.Name == "_" {
+			ident.Name, fixed = name, true
+		}
+		return !fixed
+	})
+	return fixed
+}
@@ -0,0 +1,75 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionEval_Result(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		"_ + 1",
+		"1 + 2",
+		"_ * 10",
+		"_2 + _3",
+		":type _",
+		"x := []int{_, _2}",
+		"for _, v := range x { _ = v }",
+		"nil",
+		"_",
+		`:import "strconv"`,
+		`strconv.Atoi("42")`,
+		"_",
+		"_4 - _",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `3
+30
+33
+int
+[]int{33, 3}
+<nil>
+33
+42
+<nil>
+33
+0
+`, stdout.String())
+	assert.Equal(t, "cannot use _ as value or type\n", stderr.String())
+}
+
+func TestSessionEval_ResultPersist(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+	s.persist = true
+
+	codes := []string{
+		`:import "fmt"`,
+		`fmt.Sprint("hello")`,
+		`_ + " world"`,
+		`_1 + "!"`,
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `"hello"
+"hello world"
+"hello!"
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
@@ -0,0 +1,157 @@
+package gore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+// The server provides the session over JSON-RPC 2.0, with the base protocol of
+// the Language Server Protocol (messages with Content-Length headers).
+// The methods are the following (the positions are byte offsets):
+//
+//	eval     {"code": string} -> evalResult
+//	complete {"line": string, "pos": int} -> {"prefix": string, "candidates": []string, "suffix": string}
+//	type     {"expr": string} -> {"type": string}
+//	doc      {"expr": string} -> {"doc": string}
+type server struct {
+	session *Session
+	mu      sync.Mutex // serializes the requests from the connections
+}
+
+// evalResult is the result of the eval method.
+type evalResult struct {
+	EvalResult
+	// Error is one of "ErrContinue", "ErrCmdRun", "ErrQuit" and "error",
+	// or empty if the code is evaluated successfully.
+	Error   string `json:"error,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// runServer serves the session on the standard input and output, or on the
+// unix domain socket if the address is specified, until the context is done.
+func (g *Gore) runServer(ctx context.Context, addr string) error {
+	srv := &server{}
+	s, err := g.newSession(io.Discard, io.Discard)
+	defer s.Clear()
+	if err != nil {
+		return err
+	}
+	// the standard input is used for the requests
+	s.stdin = nil
+	srv.session = s
+
+	if err := s.initCompleter(); err != nil {
+		debugf("failed to initialize gopls completer: %s", err)
+	}
+
+	if err := g.include(s); err != nil {
+		return err
+	}
+
+	if addr != "" {
+		l, err := net.Listen("unix", addr)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+		return jsonrpc2.Serve(ctx, l, jsonrpc2.HandlerServer(srv.handle), 0)
+	}
+
+	conn := jsonrpc2.NewConn(jsonrpc2.NewStream(stdio{os.Stdin, g.outWriter}))
+	conn.Go(ctx, srv.handle)
+	select {
+	case <-conn.Done():
+	case <-ctx.Done():
+		conn.Close()
+		return ctx.Err()
+	}
+	if err := conn.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func (srv *server) handle(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	debugf("serve :: %s %s", req.Method(), req.Params())
+	var params struct {
+		Code string `json:"code"`
+		Line string `json:"line"`
+		Pos  int    `json:"pos"`
+		Expr string `json:"expr"`
+	}
+	if len(req.Params()) > 0 {
+		if err := json.Unmarshal(req.Params(), &params); err != nil {
+			return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidParams, err.Error()))
+		}
+	}
+
+	switch req.Method() {
+	case "eval":
+		return reply(ctx, srv.eval(ctx, params.Code), nil)
+	case "complete":
+		if params.Pos < 0 || params.Pos > len(params.Line) {
+			return reply(ctx, nil, jsonrpc2.Errorf(jsonrpc2.InvalidParams, "invalid position: %d", params.Pos))
+		}
+		prefix, candidates, suffix := srv.session.completeWord(params.Line, params.Pos)
+		if candidates == nil {
+			candidates = []string{}
+		}
+		return reply(ctx, map[string]any{"prefix": prefix, "candidates": candidates, "suffix": suffix}, nil)
+	case "type":
+		typ, err := srv.session.exprType(params.Expr)
+		if err != nil {
+			return reply(ctx, nil, err)
+		}
+		return reply(ctx, map[string]string{"type": typeString(typ)}, nil)
+	case "doc":
+		doc, err := srv.session.captureOutput(func() error {
+			return actionDoc(srv.session, params.Expr)
+		})
+		if err != nil {
+			return reply(ctx, nil, err)
+		}
+		return reply(ctx, map[string]string{"doc": doc}, nil)
+	default:
+		return jsonrpc2.MethodNotFoundHandler(ctx, reply, req)
+	}
+}
+
+func (srv *server) eval(ctx context.Context, code string) *evalResult {
+	res, err := srv.session.EvalContext(ctx, code)
+	result := evalResult{EvalResult: *res}
+	// editors do not need the colors of the printer
+	result.Stdout = escapeSequencePattern.ReplaceAllString(result.Stdout, "")
+	if err != nil {
+		result.Message = err.Error()
+		switch err {
+		case ErrContinue:
+			result.Error = "ErrContinue"
+		case ErrCmdRun:
+			result.Error = "ErrCmdRun"
+		case ErrQuit:
+			result.Error = "ErrQuit"
+		default:
+			result.Error = "error"
+		}
+	}
+	return &result
+}
+
+type stdio struct {
+	io.Reader
+	io.Writer
+}
+
+func (stdio) Close() error {
+	return nil
+}
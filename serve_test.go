@@ -0,0 +1,90 @@
+package gore
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.lsp.dev/jsonrpc2"
+)
+
+func TestGore_runServer(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "gore.sock")
+
+	serverCtx, stop := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- New(Serve(addr)).runServer(serverCtx, addr)
+	}()
+	t.Cleanup(func() {
+		stop()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+
+	var c net.Conn
+	var err error
+	require.Eventually(t, func() bool {
+		c, err = net.Dial("unix", addr)
+		return err == nil
+	}, time.Minute, 100*time.Millisecond)
+
+	ctx := context.Background()
+	conn := jsonrpc2.NewConn(jsonrpc2.NewStream(c))
+	conn.Go(ctx, jsonrpc2.MethodNotFoundHandler)
+	t.Cleanup(func() { conn.Close() })
+
+	var result evalResult
+	_, err = conn.Call(ctx, "eval", map[string]string{"code": "x := 40"}, &result)
+	require.NoError(t, err)
+	assert.Equal(t, evalResult{EvalResult: EvalResult{Stdout: "40\n"}}, result)
+
+	result = evalResult{}
+	_, err = conn.Call(ctx, "eval", map[string]string{"code": "x + 2"}, &result)
+	require.NoError(t, err)
+	assert.Equal(t, evalResult{EvalResult: EvalResult{Stdout: "42\n", Type: "int"}}, result)
+
+	result = evalResult{}
+	_, err = conn.Call(ctx, "eval", map[string]string{"code": "y"}, &result)
+	require.NoError(t, err)
+	assert.Equal(t, "undefined: y\n", result.Stderr)
+	assert.Equal(t, "ErrCmdRun", result.Error)
+	assert.Equal(t, "<command failed>", result.Message)
+	require.Len(t, result.Diagnostics, 1)
+	assert.Equal(t, 3, result.Diagnostics[0].Input)
+
+	result = evalResult{}
+	_, err = conn.Call(ctx, "eval", map[string]string{"code": "func f() {"}, &result)
+	require.NoError(t, err)
+	assert.Equal(t, evalResult{Error: "ErrContinue", Message: "<continue input>"}, result)
+
+	var typ struct{ Type string }
+	_, err = conn.Call(ctx, "type", map[string]string{"expr": "[]int{x}"}, &typ)
+	require.NoError(t, err)
+	assert.Equal(t, "[]int", typ.Type)
+
+	_, err = conn.Call(ctx, "type", map[string]string{"expr": "z"}, &typ)
+	require.Error(t, err)
+
+	_, err = conn.Call(ctx, "eval", map[string]string{"code": "type T struct{ A int }"}, &result)
+	require.NoError(t, err)
+	_, err = conn.Call(ctx, "type", map[string]string{"expr": "&T{x}"}, &typ)
+	require.NoError(t, err)
+	assert.Equal(t, "*T", typ.Type)
+
+	var completion struct {
+		Prefix     string
+		Candidates []string
+		Suffix     string
+	}
+	_, err = conn.Call(ctx, "complete", map[string]any{"line": ":cl", "pos": 3}, &completion)
+	require.NoError(t, err)
+	assert.Equal(t, ":", completion.Prefix)
+	assert.Equal(t, []string{"clear"}, completion.Candidates)
+
+	_, err = conn.Call(ctx, "foo", nil, nil)
+	require.ErrorContains(t, err, "method not found")
+}
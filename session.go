@@ -2,6 +2,7 @@ package gore
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"go/ast"
@@ -15,8 +16,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"slices"
 	"strings"
+	"syscall"
+	"time"
 	"unicode"
 
 	"golang.org/x/tools/go/packages"
@@ -35,12 +39,40 @@ type Session struct {
 	typeInfo        types.Info
 	extraFilePaths  []string
 	extraFiles      []*ast.File
+	includes        []string
+	printer         valuePrinter
+	showType        bool
 	autoImport      bool
 	requiredModules []string
 	mainBody        *ast.BlockStmt
 	lastStmts       []ast.Stmt
 	lastDecls       []ast.Decl
+	inputCount      int
+	inputLog        []loggedInput
+	stmtInputs      map[ast.Stmt]int
+	persist         bool
+	stateReady      bool
+	stateGlobals    map[string]bool // the variables at the package level in the state
+	restoreOnly     bool            // fails instead of running the previous inputs
+	undoStack       []*snapshot
+	redoStack       []*snapshot
+	showTime        bool
+	buildTime       time.Duration
+	runTime         time.Duration
+	diagnostics     []Diagnostic
+	prompt          string
+	pager           string
+	buildTags       string
+	timeout         time.Duration
+	wait            time.Duration
+	memoryLimit     uint64
+	cpuLimit        time.Duration
+	maxElems        int
+	maxDepth        int
+	maxString       int
+	maxBytes        uint64
 	completer       *goplsCompleter
+	stdin           io.Reader
 	stdout          io.Writer
 	stderr          io.Writer
 }
@@ -65,15 +97,15 @@ func main() {
 // printerPkgs is a list of packages that provides pretty printing function
 // when changing this, read listModuleDirectives carefully
 var printerPkgs = []struct {
+	name          string // the name of the printer
 	path, version string
 	requires      []pathVersion
-	code          string
 }{
 	{
-		path: "github.com/k0kubun/pp/v3", version: "v3.1.0", code: `pp.Println(x)`,
+		name: "pp", path: "github.com/k0kubun/pp/v3", version: "v3.1.0",
 		requires: []pathVersion{{"github.com/mattn/go-colorable", "v0.1.12"}},
 	},
-	{path: "fmt", code: `fmt.Printf("%#v\n", x)`},
+	{name: "%#v", path: "fmt"},
 }
 
 type pathVersion struct {
@@ -84,7 +116,15 @@ type pathVersion struct {
 func NewSession(stdout, stderr io.Writer) (*Session, error) {
 	var err error
 
-	s := &Session{stdout: stdout, stderr: stderr}
+	s := &Session{
+		stdin:  os.Stdin,
+		stdout: stdout,
+		stderr: stderr,
+		prompt: promptDefault,
+		pager:  os.Getenv("GORE_PAGER"),
+
+		maxBytes: 64 << 10,
+	}
 
 	s.tempDir, err = os.MkdirTemp("", "gore-")
 	if err != nil {
@@ -126,11 +166,17 @@ func (s *Session) init() (err error) {
 	s.typeInfo = types.Info{}
 	s.extraFilePaths = nil
 	s.extraFiles = nil
+	s.includes = nil
+	s.inputLog = nil
 
 	if err = s.initGoMod(); err != nil { // this should be before printer load for printer package requirements
 		return err
 	}
 
+	// the printer selected before clearing the session is kept
+	selected := s.printer.name
+	s.printer = valuePrinter{}
+
 	var initialSource string
 	for _, pp := range printerPkgs {
 		_, err = packages.Load(
@@ -141,7 +187,8 @@ func (s *Session) init() (err error) {
 			pp.path,
 		)
 		if err == nil {
-			initialSource = fmt.Sprintf(initialSourceTemplate, pp.path, pp.code)
+			s.printer, _ = lookupPrinter(pp.name)
+			initialSource = fmt.Sprintf(initialSourceTemplate, pp.path, s.printer.code)
 			break
 		}
 		debugf("could not import %q: %s", pp.path, err)
@@ -158,10 +205,18 @@ func (s *Session) init() (err error) {
 
 	s.mainBody = s.mainFunc().Body
 
+	if _, ok := lookupPrinter(selected); ok && selected != s.printer.name {
+		if err := s.setPrinter(selected); err != nil {
+			debugf("could not set printer %q: %s", selected, err)
+		}
+	}
+
 	s.lastStmts = nil
 	s.lastDecls = nil
+	s.inputCount = 0
+	s.stmtInputs = map[ast.Stmt]int{}
 
-	return nil
+	return s.resetState()
 }
 
 func (s *Session) initCompleter() error {
@@ -185,31 +240,158 @@ func (s *Session) mainFunc() *ast.FuncDecl {
 
 // Run the session.
 func (s *Session) Run() error {
+	return s.run(context.Background())
+}
+
+func (s *Session) run(ctx context.Context) error {
+	if s.persist {
+		return s.runPersistent(ctx)
+	}
+
 	f, err := os.Create(s.tempFilePath)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
+	// the statements of the built file correspond to the ones of the session
+	// while running, for the stack traces
+	defer s.useWaiter()()
+	restore := s.useLimitedPrinter()
+	// the closing brace out of the files puts the statements on separate
+	// lines, so that the lines of the panics locate the statements
+	body := s.mainFunc().Body
+	rbrace := body.Rbrace
+	body.Rbrace = token.Pos(s.fset.Base())
 	err = printer.Fprint(f, s.fset, s.file)
+	body.Rbrace = rbrace
+	restore()
 	if err != nil {
 		return err
 	}
 
-	return s.goRun(append(s.extraFilePaths, s.tempFilePath))
+	return s.goRun(ctx, append(s.extraFilePaths, s.tempFilePath))
 }
 
-func (s *Session) goRun(files []string) error {
-	args := append([]string{"run", "-mod=mod"}, files...)
-	debugf("go %s", strings.Join(args, " "))
-	cmd := exec.Command("go", args...)
-	cmd.Stdin = os.Stdin
+// waitDelay is the time to wait for the outputs of the processes spawned by
+// the killed command.
+const waitDelay = time.Second
+
+func (s *Session) goRun(ctx context.Context, files []string) error {
+	bin := filepath.Join(s.tempDir, "gore_session")
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+
+	printFilePath := filepath.Join(s.tempDir, "gore_print.go")
+	if err := os.WriteFile(printFilePath, []byte(printSource), 0o644); err != nil {
+		return err
+	}
+	files = append(files, printFilePath)
+	env := append(os.Environ(), s.printEnv()...)
+
+	if s.memoryLimit > 0 || s.cpuLimit > 0 {
+		limitDir := filepath.Join(s.tempDir, "gore_limit")
+		if err := os.MkdirAll(limitDir, 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(limitDir, "limit.go"), []byte(limitSource), 0o644); err != nil {
+			return err
+		}
+		// the module of the temporary directory is named by initGoMod
+		limitFilePath := filepath.Join(s.tempDir, "gore_limit.go")
+		importSource := fmt.Sprintf("package main\n\nimport _ %q\n", filepath.Base(s.tempDir)+"/gore_limit")
+		if err := os.WriteFile(limitFilePath, []byte(importSource), 0o644); err != nil {
+			return err
+		}
+		files = append(files, limitFilePath)
+		env = append(env, limitEnv(s.memoryLimit, s.cpuLimit)...)
+	}
+
+	if s.wait > 0 {
+		waitFilePath := filepath.Join(s.tempDir, "gore_wait.go")
+		if err := os.WriteFile(waitFilePath, []byte(waitSource), 0o644); err != nil {
+			return err
+		}
+		files = append(files, waitFilePath)
+		env = append(env, waitEnv(s.wait)...)
+	}
+
+	start := time.Now()
+	if err := s.goBuild(ctx, bin, files); err != nil {
+		return err
+	}
+	s.buildTime = time.Since(start)
+
+	runCtx := ctx
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	debugf("run %s", bin)
+	// the processes spawned by the program are killed with it on cancellation,
+	// and the program runs in the foreground to read the terminal
+	cmd := exec.CommandContext(runCtx, bin)
+	takeTerminal := setForegroundProcessGroup(cmd, s.stdin)
+	cmd.WaitDelay = waitDelay
+	cmd.Env = env
+	cmd.Stdin = s.stdin
 	cmd.Stdout = s.stdout
 	cmd.Dir = s.tempDir
-	ef := newErrFilter(s.stderr)
+	ef := newErrFilter(s.stderr, s.frameLocator())
 	cmd.Stderr = ef
 	defer ef.Close()
-	return cmd.Run()
+	start = time.Now()
+	err := cmd.Run()
+	takeTerminal()
+	s.runTime = time.Since(start)
+	s.saveFullOutput()
+	if s.showTime {
+		fmt.Fprintf(s.stderr, "build: %s, run: %s\n",
+			s.buildTime.Round(time.Millisecond), s.runTime.Round(time.Millisecond))
+	}
+	if err != nil && ctx.Err() == nil {
+		if runCtx.Err() != nil {
+			fmt.Fprintf(s.stderr, "timeout: the program ran longer than %s\n", s.timeout)
+		} else if err, ok := err.(*exec.ExitError); ok {
+			if status, ok := err.Sys().(syscall.WaitStatus); ok && status.Signaled() && status.Signal() == syscall.SIGINT {
+				// the program in the foreground receives Ctrl-C instead of gore
+				fmt.Fprintln(s.stderr, "interrupted")
+			} else if s.cpuLimit > 0 && err.UserTime()+err.SystemTime() >= s.cpuLimit {
+				fmt.Fprintf(s.stderr, "cpulimit: the program used CPU time longer than %s\n", s.cpuLimit)
+			}
+		}
+	}
+	return err
+}
+
+// goBuild builds the program. The packages the program depends on are cached
+// by the go command, so the session package is the only one compiled for each
+// input.
+func (s *Session) goBuild(ctx context.Context, bin string, files []string) error {
+	args := []string{"build", "-mod=mod", "-ldflags=-s -w", "-o", bin}
+	if s.buildTags != "" {
+		args = append(args, "-tags="+s.buildTags)
+	}
+	args = append(args, files...)
+	debugf("go %s", strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, "go", args...)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = waitDelay
+	cmd.Stdout = s.stdout
+	cmd.Dir = s.tempDir
+	ef := newErrFilter(s.stderr, nil)
+	var output bytes.Buffer
+	cmd.Stderr = io.MultiWriter(ef, &output)
+	defer ef.Close()
+	s.diagnostics = nil
+	if err := cmd.Run(); err != nil {
+		s.diagnostics = s.parseDiagnostics(output.String())
+		return err
+	}
+	return nil
 }
 
 func (s *Session) evalExpr(in string) (ast.Expr, error) {
@@ -248,6 +430,8 @@ func (s *Session) evalStmt(in string) error {
 	var stmts []ast.Stmt
 
 	for _, stmt := range enclosingFunc.Body.List {
+		global := s.isGlobalDecl(stmt)
+		s.redefine(stmt, global)
 		switch stmt := stmt.(type) {
 		case *ast.AssignStmt:
 			if stmt := buildPrintStmt(stmt.Lhs); stmt != nil {
@@ -255,16 +439,17 @@ func (s *Session) evalStmt(in string) error {
 			}
 		case *ast.DeclStmt:
 			if decl, ok := stmt.Decl.(*ast.GenDecl); ok {
-				if decl.Tok == token.TYPE {
+				if stmt := buildPrintStmtOfDecl(decl); stmt != nil {
+					stmts = append(stmts, stmt)
+				}
+				if global {
 					for i, d := range s.file.Decls {
-						if d, ok := d.(*ast.FuncDecl); ok && d.Name.String() == "main" {
+						if d, ok := d.(*ast.FuncDecl); ok && funcKey(d) == "main" {
 							s.file.Decls = slices.Insert(s.file.Decls, i, stmt.Decl)
 							break
 						}
 					}
 					continue
-				} else if stmt := buildPrintStmtOfDecl(decl); stmt != nil {
-					stmts = append(stmts, stmt)
 				}
 			}
 		}
@@ -275,6 +460,47 @@ func (s *Session) evalStmt(in string) error {
 	return nil
 }
 
+// isGlobalDecl reports whether the statement is a declaration which should be
+// placed at the package level, so that the functions can refer to it. The
+// types are always declared there, and the variables and the constants are
+// unless they refer to the variables in main.
+func (s *Session) isGlobalDecl(stmt ast.Stmt) bool {
+	decl, ok := stmt.(*ast.DeclStmt)
+	if !ok {
+		return false
+	}
+	gen, ok := decl.Decl.(*ast.GenDecl)
+	if !ok {
+		return false
+	}
+	if gen.Tok == token.TYPE {
+		return true
+	}
+
+	s.checkSession()
+	scope := s.typeInfo.Scopes[s.mainFunc().Type]
+	if scope == nil {
+		return true
+	}
+	// the variables redeclared in main are also kept there to shadow them
+	global := true
+	var inspect func(ast.Node) bool
+	inspect = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			ast.Inspect(n.X, inspect)
+			return false
+		case *ast.Ident:
+			if scope.Lookup(n.Name) != nil {
+				global = false
+			}
+		}
+		return global
+	}
+	ast.Inspect(gen, inspect)
+	return global
+}
+
 func buildPrintStmt(exprs []ast.Expr) ast.Stmt {
 	vs := make([]ast.Expr, 0, len(exprs))
 	for _, v := range exprs {
@@ -323,18 +549,18 @@ func (s *Session) evalFunc(in string) error {
 	if len(f.Decls) != 1 {
 		return errors.New("eval func error")
 	}
-	decl, name := f.Decls[0], ""
+	decl, key := f.Decls[0], ""
 	d, ok := decl.(*ast.FuncDecl)
 	if !ok {
 		return errors.New("eval func error")
 	}
-	name = d.Name.String()
+	key = funcKey(d)
 	for i, d := range s.file.Decls {
 		if d, ok := d.(*ast.FuncDecl); ok {
-			if d.Name.String() == name {
+			if funcKey(d) == key {
 				s.file.Decls[i] = decl
 				break
-			} else if d.Name.String() == "main" {
+			} else if funcKey(d) == "main" {
 				s.file.Decls = slices.Insert(s.file.Decls, i, decl)
 				break
 			}
@@ -343,6 +569,32 @@ func (s *Session) evalFunc(in string) error {
 	return nil
 }
 
+// funcKey returns the name of the function, or the name of the method
+// qualified by the receiver type (e.g. T.String), which identifies the
+// declaration in the session.
+func funcKey(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return decl.Name.Name
+	}
+	typ := decl.Recv.List[0].Type
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+		case *ast.ParenExpr:
+			typ = t.X
+		case *ast.IndexExpr:
+			typ = t.X
+		case *ast.IndexListExpr:
+			typ = t.X
+		case *ast.Ident:
+			return t.Name + "." + decl.Name.Name
+		default:
+			return decl.Name.Name
+		}
+	}
+}
+
 func (*Session) parseTokens(in string) error {
 	var sc scanner.Scanner
 	fset := token.NewFileSet()
@@ -361,9 +613,36 @@ func (*Session) parseTokens(in string) error {
 }
 
 func (s *Session) appendStatements(stmts ...ast.Stmt) {
+	for _, stmt := range stmts {
+		s.stmtInputs[stmt] = s.inputCount
+	}
 	s.mainBody.List = append(s.mainBody.List, stmts...)
 }
 
+// inputOf returns the number of the input which the i-th statement of main
+// came from. Statements inserted by quick fixes belong to the preceding one.
+func (s *Session) inputOf(i int) int {
+	for ; i >= 0; i-- {
+		if n, ok := s.stmtInputs[s.mainBody.List[i]]; ok {
+			return n
+		}
+	}
+	return 0
+}
+
+// retagStatements carries input numbers of the statements over to the
+// reparsed main body, which has the statements in the same order.
+func (s *Session) retagStatements(stmts []ast.Stmt) {
+	for i, stmt := range s.mainBody.List {
+		if i >= len(stmts) {
+			break
+		}
+		if n, ok := s.stmtInputs[stmts[i]]; ok {
+			s.stmtInputs[stmt] = n
+		}
+	}
+}
+
 // Error ...
 type Error string
 
@@ -409,28 +688,47 @@ func (s *Session) reset() error {
 		return err
 	}
 
+	stmts := s.mainBody.List
 	s.file = file
 	s.mainBody = s.mainFunc().Body
+	s.retagStatements(stmts)
 
 	return nil
 }
 
 // Eval the input.
 func (s *Session) Eval(in string) error {
+	return s.eval(context.Background(), in)
+}
+
+func (s *Session) eval(ctx context.Context, in string) error {
 	debugf("eval >>> %q", in)
 
 	s.clearQuickFix()
 	s.storeCode()
 
+	snap, err := s.snapshot()
+	if err != nil {
+		debugf("snapshot :: err = %s", err)
+	}
+
 	if strings.HasPrefix(strings.TrimSpace(in), ":") {
+		undos, redos := len(s.undoStack), len(s.redoStack)
 		err := s.invokeCommand(in)
 		if err != nil && err != ErrQuit {
 			fmt.Fprintf(s.stderr, "%s\n", err)
 		}
+		// commands like :undo manage the history by themselves
+		if err == nil && len(s.undoStack) == undos && len(s.redoStack) == redos {
+			s.recordHistory(snap, in, 0)
+		}
 		return err
 	}
 
-	if _, err := s.evalExpr(in); err != nil {
+	s.inputCount++
+	_, err = s.evalExpr(in)
+	isExpr := err == nil
+	if err != nil {
 		debugf("expr :: err = %s", err)
 
 		err := s.evalStmt(in)
@@ -441,6 +739,7 @@ func (s *Session) Eval(in string) error {
 			if err != nil {
 				debugf("func :: err = %s", err)
 
+				s.inputCount--
 				if err := s.parseTokens(in); err != nil {
 					fmt.Fprintf(s.stderr, "%s\n", err)
 					return err
@@ -456,16 +755,33 @@ func (s *Session) Eval(in string) error {
 			debugf("fixImports :: err = %s", err)
 		}
 	}
+	s.checkPrinter()
 	s.doQuickFix()
+	// the types of the result variable are needed for the persistent mode
+	if isExpr && s.bindResult() {
+		s.doQuickFix()
+	}
 
-	err := s.Run()
+	err = s.run(ctx)
 	if err != nil {
-		if _, ok := err.(*exec.ExitError); ok {
+		if _, ok := err.(*exec.ExitError); ok || ctx.Err() != nil {
 			debugf("got exit error, popping out last input")
-			s.restoreCode()
+			// the redefined declarations are renamed in place
+			if snap == nil || s.restoreSource(snap) != nil {
+				s.restoreCode()
+			}
 		}
 		debugf("%s", err)
 		err = ErrCmdRun
+	} else {
+		// inputs just printing values do not need to be undone, but the
+		// results are kept for the later inputs
+		s.clearQuickFix()
+		if isExpr && s.isPureResult() {
+			s.logInput(in, s.inputCount)
+		} else {
+			s.recordHistory(snap, in, s.inputCount)
+		}
 	}
 
 	s.updateCompleter()
@@ -515,6 +831,13 @@ func (s *Session) invokeCommand(in string) (err error) {
 func (s *Session) storeCode() {
 	s.lastStmts = s.mainBody.List
 	s.lastDecls = slices.Clone(s.file.Decls)
+
+	// forget the statements no longer in the session
+	inputs := make(map[ast.Stmt]int, len(s.mainBody.List))
+	for i, stmt := range s.mainBody.List {
+		inputs[stmt] = s.inputOf(i)
+	}
+	s.stmtInputs = inputs
 }
 
 // restoreCode restores the previous code
@@ -522,9 +845,9 @@ func (s *Session) restoreCode() {
 	s.mainBody.List = s.lastStmts
 	decls := make([]ast.Decl, 0, len(s.file.Decls))
 	for _, d := range s.file.Decls {
-		if d, ok := d.(*ast.FuncDecl); ok && d.Name.String() != "main" {
+		if d, ok := d.(*ast.FuncDecl); ok && funcKey(d) != "main" {
 			for _, ld := range s.lastDecls {
-				if ld, ok := ld.(*ast.FuncDecl); ok && ld.Name.String() == d.Name.String() {
+				if ld, ok := ld.(*ast.FuncDecl); ok && funcKey(ld) == funcKey(d) {
 					decls = append(decls, ld)
 					break
 				}
@@ -539,27 +862,34 @@ func (s *Session) restoreCode() {
 // includeFiles imports packages and funcsions from multiple golang source
 func (s *Session) includeFiles(files []string) {
 	for _, file := range files {
-		s.includeFile(file)
+		if s.includeFile(file) {
+			if abs, err := filepath.Abs(file); err == nil {
+				file = abs
+			}
+			s.includes = append(s.includes, "context "+file)
+		}
 	}
 }
 
-func (s *Session) includeFile(file string) {
+func (s *Session) includeFile(file string) bool {
 	content, err := os.ReadFile(file)
 	if err != nil {
 		errorf("%s", err)
-		return
+		return false
 	}
 
 	if err = s.importPackages(content); err != nil {
 		errorf("%s", err)
-		return
+		return false
 	}
 
 	if err = s.importFile(content); err != nil {
 		errorf("%s", err)
+		return false
 	}
 
 	infof("added file %s", file)
+	return true
 }
 
 // importPackages includes packages defined on external file into main file
@@ -654,11 +984,13 @@ func (s *Session) fixImports() error {
 		return err
 	}
 
+	stmts := s.mainBody.List
 	s.file, err = parser.ParseFile(s.fset, "", formatted, parser.Mode(0))
 	if err != nil {
 		return err
 	}
 	s.mainBody = s.mainFunc().Body
+	s.retagStatements(stmts)
 
 	return nil
 }
@@ -675,11 +1007,10 @@ func (s *Session) includePackage(path string) error {
 		}
 	}
 
-	files := make([]string, len(pkg.GoFiles))
-	for i, f := range pkg.GoFiles {
-		files[i] = filepath.Join(pkg.Dir, f)
+	for _, f := range pkg.GoFiles {
+		s.includeFile(filepath.Join(pkg.Dir, f))
 	}
-	s.includeFiles(files)
+	s.includes = append(s.includes, "pkg "+path)
 
 	return nil
 }
@@ -691,3 +1022,15 @@ func (s *Session) Clear() error {
 	}
 	return os.RemoveAll(s.tempDir)
 }
+
+// captureOutput runs f with the outputs of the session written to a buffer,
+// and returns the outputs.
+func (s *Session) captureOutput(f func() error) (string, error) {
+	var buf syncBuffer
+	stdout, stderr := s.stdout, s.stderr
+	s.stdout, s.stderr = &buf, &buf
+	defer func() { s.stdout, s.stderr = stdout, stderr }()
+
+	err := f()
+	return buf.String(), err
+}
@@ -253,6 +253,43 @@ func TestSessionEval_Declarations(t *testing.T) {
 	assert.Equal(t, "", stderr.String())
 }
 
+func TestSessionEval_GlobalDeclarations(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		`var count int`,
+		`const step = 2`,
+		`func inc() int { count += step; return count }`,
+		`inc()`,
+		`inc()`,
+		`x := 10`,
+		`var y = x * 2`,
+		`func init() { count = 100 }`,
+		`inc()`,
+		`var count = 1000`,
+		`inc() + count`,
+	}
+
+	for _, code := range codes {
+		err := s.Eval(code)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, `2
+2
+4
+10
+20
+106
+1000
+1108
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
 func TestSessionEval_NotUsed(t *testing.T) {
 	var stdout, stderr strings.Builder
 	s, err := NewSession(&stdout, &stderr)
@@ -470,3 +507,145 @@ var V = 42
 int`)
 	assert.Equal(t, ``, stderr.String())
 }
+
+func TestSessionEval_Persist(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+	s.persist = true
+
+	codes := []string{
+		`:import fmt`,
+		`fmt.Println("hello")`,
+		`x := 1`,
+		`x++`,
+		`type T struct { A []int }`,
+		`t := T{[]int{x}}`,
+		`f := func() int { return x * 10 }`,
+		`t.A = append(t.A, f())`,
+		`t`,                       // the result shares the slice with t
+		`ch := make(chan int, 1)`, // channels cannot be saved
+		`x`,
+	}
+
+	for _, code := range codes {
+		err := s.Eval(code)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, `hello
+6
+<nil>
+1
+main.T{A:[]int{2}}
+(func() int)(...)
+[]int{2, 20}
+main.T{A:[]int{2, 20}}
+hello
+(chan int)(...)
+hello
+2
+`, regexp.MustCompile(`0x[0-9a-f]+`).ReplaceAllString(stdout.String(), "..."))
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestSessionEval_Persist_Globals(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+	s.persist = true
+
+	codes := []string{
+		`:import fmt`,
+		`fmt.Println("hello")`,
+		`var n = 1`,
+		`n++`,
+		`func f() int { return n * 10 }`,
+		`var m = f()`,
+		`n * m`,
+		`var n = "a"`,
+		`n + "b"`,
+		`m`,
+	}
+
+	for _, code := range codes {
+		err := s.Eval(code)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, `hello
+6
+<nil>
+1
+10
+20
+hello
+"a"
+"ab"
+10
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestSessionEval_Persist_Shared(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+	s.persist = true
+
+	codes := []string{
+		`x := 1`,
+		`p := &x`,
+		`*p = 5`,
+		`x`,
+		`a := []int{1, 2}`,
+		`b := a`,
+		`b[0] = 9`,
+		`a`,
+	}
+
+	for _, code := range codes {
+		err := s.Eval(code)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, `1
+(*int)(...)
+5
+5
+[]int{1, 2}
+[]int{1, 2}
+9
+[]int{9, 2}
+`, regexp.MustCompile(`0x[0-9a-f]+`).ReplaceAllString(stdout.String(), "..."))
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestSessionEval_Panic(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		"func f(xs []int) int {\n\tx := 0\n\treturn xs[5] + x\n}",
+		"xs := []int{1, 2, 3}",
+		"len(xs)",
+		"for i := range 2 {\n\tif i == 1 {\n\t\tf(xs)\n\t}\n}",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, "[]int{1, 2, 3}\n3\n", stdout.String())
+	assert.Equal(t, `panic: runtime error: index out of range [5] with length 3
+
+goroutine 1 [running]:
+    f at line 3 of func f
+    main at line 3 of input 4
+`, stderr.String())
+}
@@ -0,0 +1,169 @@
+package gore
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A session file holds the inputs of a session, so that it can be replayed
+// into a fresh session. The inputs are written as they are typed at the
+// prompt; an input spans multiple lines while it is incomplete. Lines starting
+// with "//" out of inputs are comments, except for the following directives:
+//
+//	//gore:context <file>    include the Go source file (like -context)
+//	//gore:pkg <package>     include the package (like -pkg)
+//	//gore:input <n>         number the next input n (for the result _n)
+//
+// For example:
+//
+//	// gore session
+//	//gore:context /path/to/util.go
+//	:import fmt strings
+//	s := strings.Repeat("a", 3)
+//	func f(s string) string {
+//	    return fmt.Sprintf("<%s>", s)
+//	}
+//	f(s)
+const sessionFileHeader = "// gore session"
+
+const directivePrefix = "//gore:"
+
+// save writes the session file of the current session.
+func (s *Session) save(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, sessionFileHeader)
+
+	for _, include := range s.includes {
+		fmt.Fprintln(bw, directivePrefix+include)
+	}
+
+	var paths []string
+	for _, imp := range s.file.Imports {
+		if path, _ := strconv.Unquote(imp.Path.Value); !slices.Contains(s.printer.imports, path) {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) > 0 {
+		fmt.Fprintln(bw, ":import "+strings.Join(paths, " "))
+	}
+
+	// the numbers of the inputs are kept for the result variables (_N)
+	next := 1
+	for _, in := range s.inputLog {
+		if in.n > 0 {
+			if in.n != next {
+				fmt.Fprintf(bw, "%sinput %d\n", directivePrefix, in.n)
+			}
+			next = in.n + 1
+		}
+		if strings.HasPrefix(strings.TrimSpace(in.in), ":import ") {
+			continue // already imported above
+		}
+		fmt.Fprintln(bw, in.in)
+	}
+
+	return bw.Flush()
+}
+
+// load replays the session file.
+func (s *Session) load(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return s.evalReader(f, name)
+}
+
+// evalReader evaluates the inputs read from r in the session file format,
+// and stops at the first input which fails.
+func (s *Session) evalReader(r io.Reader, name string) error {
+	return s.evalReaderContext(context.Background(), r, name)
+}
+
+// evalReaderContext is like evalReader, but the program is killed and the rest
+// of the inputs are discarded when the context is done.
+func (s *Session) evalReaderContext(ctx context.Context, r io.Reader, name string) error {
+	sc := bufio.NewScanner(r)
+	var buffer string
+	var lineno, start int
+	for sc.Scan() {
+		line := sc.Text()
+		lineno++
+
+		if buffer == "" {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if strings.HasPrefix(line, directivePrefix) {
+				if err := s.evalDirective(strings.TrimPrefix(line, directivePrefix)); err != nil {
+					return fmt.Errorf("%s:%d: %w", name, lineno, err)
+				}
+				continue
+			}
+			if strings.HasPrefix(line, "//") {
+				continue
+			}
+			buffer, start = line, lineno
+		} else {
+			buffer += "\n" + line
+		}
+
+		if depth := countDepth(buffer); depth < 0 {
+			return fmt.Errorf("%s:%d: %w", name, lineno, errUnmatchedBraces)
+		} else if depth > 0 {
+			continue
+		}
+
+		if err := s.eval(ctx, buffer); err != nil {
+			if err == ErrContinue {
+				continue
+			}
+			if err == ErrQuit {
+				return nil
+			}
+			return fmt.Errorf("%s:%d: %w", name, start, err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		buffer = ""
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	if buffer != "" {
+		return fmt.Errorf("%s:%d: unexpected EOF", name, start)
+	}
+
+	return nil
+}
+
+func (s *Session) evalDirective(directive string) error {
+	name, arg, _ := strings.Cut(directive, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "context":
+		s.includeFiles([]string{arg})
+		return nil
+	case "pkg":
+		return s.includePackage(arg)
+	case "input":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid input number: %s", arg)
+		}
+		s.inputCount = n - 1
+		return nil
+	default:
+		return fmt.Errorf("unknown directive: %s", name)
+	}
+}
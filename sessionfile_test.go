@@ -0,0 +1,81 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_evalReader(t *testing.T) {
+	testCases := []struct {
+		name, src, stdout, stderr, err string
+	}{
+		{
+			name: "inputs",
+			src: `// gore session
+:import strings
+
+x := strings.Repeat("a", 3)
+func f(s string) int {
+	// comment in an input
+	return len(s)
+}
+f(x)
+`,
+			stdout: "\"aaa\"\n3\n",
+		},
+		{
+			name:   "multiple lines",
+			src:    "x := []int{\n1,\n2,\n}\nlen(\nx)\n",
+			stdout: "[]int{1, 2}\n2\n",
+		},
+		{
+			name:   "error",
+			src:    "1 + 1\nx\n2 + 2\n",
+			stdout: "2\n",
+			stderr: "undefined: x\n",
+			err:    "test:2: <command failed>",
+		},
+		{
+			name: "unmatched braces",
+			src:  "func f() {\n}}\n",
+			err:  "test:2: unmatched braces",
+		},
+		{
+			name:   "unexpected EOF",
+			src:    "1 + 1\nfunc f() {\n",
+			stdout: "2\n",
+			err:    "test:2: unexpected EOF",
+		},
+		{
+			name: "unknown directive",
+			src:  "//gore:foo bar\n",
+			err:  "test:1: unknown directive: foo",
+		},
+		{
+			name:   "quit",
+			src:    "1\n:quit\n2\n",
+			stdout: "1\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			s, err := NewSession(&stdout, &stderr)
+			t.Cleanup(func() { s.Clear() })
+			require.NoError(t, err)
+
+			err = s.evalReader(strings.NewReader(tc.src), "test")
+			if tc.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.err)
+			}
+			assert.Equal(t, tc.stdout, stdout.String())
+			assert.Equal(t, tc.stderr, stderr.String())
+		})
+	}
+}
@@ -0,0 +1,373 @@
+package gore
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+)
+
+// setting is an option of the session which can be changed by :set command.
+type setting struct {
+	name     string
+	values   []string // candidates of the value for completion
+	get      func(*Session) string
+	set      func(*Session, string) error
+	document string
+}
+
+var settings []setting
+
+func init() {
+	settings = []setting{
+		{
+			name:     "autoimport",
+			values:   []string{"on", "off"},
+			get:      func(s *Session) string { return formatOnOff(s.autoImport) },
+			set:      func(s *Session, v string) (err error) { s.autoImport, err = parseOnOff(v); return },
+			document: "format and adjust imports automatically",
+		},
+		{
+			name:     "printer",
+			values:   printerNames(),
+			get:      (*Session).printerName,
+			set:      (*Session).setPrinter,
+			document: "the printer of the values",
+		},
+		{
+			name:     "showtype",
+			values:   []string{"on", "off"},
+			get:      func(s *Session) string { return formatOnOff(s.showType) },
+			set:      func(s *Session, v string) (err error) { s.showType, err = parseOnOff(v); return },
+			document: "show the types of the values printed",
+		},
+		{
+			name:     "maxelems",
+			values:   []string{"off", "100", "1000"},
+			get:      func(s *Session) string { return formatLimit(s.maxElems) },
+			set:      func(s *Session, v string) (err error) { s.maxElems, err = parseLimit(v); return },
+			document: "limit the elements of slices and maps printed",
+		},
+		{
+			name:     "maxdepth",
+			values:   []string{"off", "10"},
+			get:      func(s *Session) string { return formatLimit(s.maxDepth) },
+			set:      func(s *Session, v string) (err error) { s.maxDepth, err = parseLimit(v); return },
+			document: "limit the nesting of values printed",
+		},
+		{
+			name:     "maxstring",
+			values:   []string{"off", "1000"},
+			get:      func(s *Session) string { return formatLimit(s.maxString) },
+			set:      func(s *Session, v string) (err error) { s.maxString, err = parseLimit(v); return },
+			document: "limit the bytes of strings printed",
+		},
+		{
+			name:     "maxbytes",
+			values:   []string{"off", "64K", "1M"},
+			get:      func(s *Session) string { return formatSize(s.maxBytes) },
+			set:      func(s *Session, v string) (err error) { s.maxBytes, err = parseSize(v); return },
+			document: "limit the bytes of the output of a value",
+		},
+		{
+			name:     "timeout",
+			values:   []string{"off", "10s", "1m"},
+			get:      func(s *Session) string { return formatDuration(s.timeout) },
+			set:      func(s *Session, v string) (err error) { s.timeout, err = parseDuration(v); return },
+			document: "kill the program running longer than the duration",
+		},
+		{
+			name:     "wait",
+			values:   []string{"off", "1s", "10s"},
+			get:      func(s *Session) string { return formatDuration(s.wait) },
+			set:      func(s *Session, v string) (err error) { s.wait, err = parseDuration(v); return },
+			document: "wait for the goroutines at most for the duration",
+		},
+		{
+			name:   "memlimit",
+			values: []string{"off", "512M", "1G"},
+			get:    func(s *Session) string { return formatSize(s.memoryLimit) },
+			set: func(s *Session, v string) error {
+				size, err := parseSize(v)
+				if err != nil {
+					return err
+				}
+				if size > 0 && !limitsSupported {
+					return fmt.Errorf("memlimit is not supported on %s", runtime.GOOS)
+				}
+				s.memoryLimit = size
+				return nil
+			},
+			document: "limit the memory of the program",
+		},
+		{
+			name:   "cpulimit",
+			values: []string{"off", "10s", "1m"},
+			get:    func(s *Session) string { return formatDuration(s.cpuLimit) },
+			set: func(s *Session, v string) error {
+				d, err := parseDuration(v)
+				if err != nil {
+					return err
+				}
+				if d > 0 && !limitsSupported {
+					return fmt.Errorf("cpulimit is not supported on %s", runtime.GOOS)
+				}
+				s.cpuLimit = d
+				return nil
+			},
+			document: "limit the CPU time of the program",
+		},
+		{
+			name:   "pager",
+			values: []string{"off", "less", "more"},
+			get: func(s *Session) string {
+				if s.pager == "" {
+					return "off"
+				}
+				return s.pager
+			},
+			set: func(s *Session, v string) error {
+				if v == "off" {
+					v = ""
+				} else if _, err := exec.LookPath(strings.Fields(v)[0]); err != nil {
+					return err
+				}
+				s.pager = v
+				return nil
+			},
+			document: "the pager of the documents",
+		},
+		{
+			name:   "tags",
+			values: []string{"off"},
+			get: func(s *Session) string {
+				if s.buildTags == "" {
+					return "off"
+				}
+				return s.buildTags
+			},
+			set: func(s *Session, v string) error {
+				if v == "off" {
+					s.buildTags = ""
+					return nil
+				}
+				tags := strings.FieldsFunc(v, func(c rune) bool { return c == ',' || c == ' ' })
+				for _, tag := range tags {
+					if strings.TrimFunc(tag, isTagChar) != "" {
+						return fmt.Errorf("invalid build tag: %s", tag)
+					}
+				}
+				s.buildTags = strings.Join(tags, ",")
+				return nil
+			},
+			document: "the build tags of the program",
+		},
+		{
+			name: "prompt",
+			get:  func(s *Session) string { return strconv.Quote(s.prompt) },
+			set: func(s *Session, v string) error {
+				// quote the prompt to end with spaces
+				if strings.HasPrefix(v, `"`) {
+					var err error
+					if v, err = strconv.Unquote(v); err != nil {
+						return err
+					}
+				}
+				if strings.ContainsAny(v, "\n\r") {
+					return errors.New("prompt must be a line")
+				}
+				s.prompt = v
+				return nil
+			},
+			document: "the prompt of the input",
+		},
+	}
+}
+
+func printerNames() []string {
+	var names []string
+	for _, p := range printers {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+func isTagChar(c rune) bool {
+	return c == '_' || c == '.' || c == '!' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+func lookupSetting(name string) (*setting, error) {
+	for i := range settings {
+		if settings[i].name == name {
+			return &settings[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown setting: %s", name)
+}
+
+func (s *Session) set(name, value string) error {
+	st, err := lookupSetting(name)
+	if err != nil {
+		return err
+	}
+	if err := st.set(s, value); err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return nil
+}
+
+func actionSet(s *Session, arg string) error {
+	name, value, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	if name == "" {
+		return errors.New("argument is required")
+	}
+
+	if value = strings.TrimSpace(value); value == "" {
+		st, err := lookupSetting(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.stdout, "%s = %s\n", st.name, st.get(s))
+		return nil
+	}
+
+	return s.set(name, value)
+}
+
+func actionShow(s *Session, arg string) error {
+	if arg != "" {
+		return actionSet(s, arg)
+	}
+
+	w := tabwriter.NewWriter(s.stdout, 0, 8, 2, ' ', 0)
+	for _, st := range settings {
+		fmt.Fprintf(w, "%s\t= %s\t# %s\n", st.name, st.get(s), st.document)
+	}
+	return w.Flush()
+}
+
+func completeShow(_ *Session, prefix string) []string {
+	var result []string
+	for _, st := range settings {
+		if strings.HasPrefix(st.name, prefix) {
+			result = append(result, st.name)
+		}
+	}
+	return result
+}
+
+func completeSet(_ *Session, prefix string) []string {
+	var result []string
+	name, value, found := strings.Cut(prefix, " ")
+	for _, st := range settings {
+		if !found {
+			if strings.HasPrefix(st.name, name) {
+				result = append(result, st.name+" ")
+			}
+		} else if st.name == name {
+			value = strings.TrimLeft(value, " ")
+			for _, v := range st.values {
+				if strings.HasPrefix(v, value) {
+					result = append(result, prefix[:len(prefix)-len(value)]+v)
+				}
+			}
+		}
+	}
+	return result
+}
+
+func parseOnOff(s string) (bool, error) {
+	switch s {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid argument: %s", s)
+	}
+}
+
+func formatOnOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func parseLimit(s string) (int, error) {
+	if s == "off" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid limit: %s", s)
+	}
+	return n, nil
+}
+
+func formatLimit(n int) string {
+	if n == 0 {
+		return "off"
+	}
+	return strconv.Itoa(n)
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if s == "off" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration: %s", s)
+	}
+	return d, nil
+}
+
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "off"
+	}
+	return d.String()
+}
+
+var sizeUnits = []string{"K", "M", "G", "T"}
+
+// parseSize parses the size in bytes with an optional binary unit, like 512M.
+func parseSize(s string) (uint64, error) {
+	if s == "off" {
+		return 0, nil
+	}
+	v := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(s), "B"), "I")
+	var shift uint
+	for i, unit := range sizeUnits {
+		if strings.HasSuffix(v, unit) {
+			v, shift = strings.TrimSuffix(v, unit), uint(10*(i+1))
+			break
+		}
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil || n > (1<<64-1)>>shift {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return n << shift, nil
+}
+
+func formatSize(n uint64) string {
+	if n == 0 {
+		return "off"
+	}
+	for i := len(sizeUnits); i > 0; i-- {
+		if shift := uint(10 * i); n%(1<<shift) == 0 {
+			return strconv.FormatUint(n>>shift, 10) + sizeUnits[i-1]
+		}
+	}
+	return strconv.FormatUint(n, 10)
+}
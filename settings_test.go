@@ -0,0 +1,145 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_Set(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		":set timeout",
+		":set timeout 1s",
+		":set timeout",
+		"for {}",
+		":set timeout off",
+		"1 + 1",
+		":set timeout -1s",
+		":set timeout foo",
+		":set foo 1",
+		":set",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, "timeout = off\ntimeout = 1s\n2\n", stdout.String())
+	assert.Equal(t, `timeout: the program ran longer than 1s
+set: invalid timeout: negative duration: -1s
+set: invalid timeout: time: invalid duration "foo"
+set: unknown setting: foo
+set: argument is required
+`, stderr.String())
+}
+
+func TestAction_Show(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		":set autoimport on",
+		":set printer json",
+		":set tags foo, bar_baz",
+		`:set prompt "go> "`,
+		":show",
+		":set tags off",
+		":show tags",
+		":set autoimport yes",
+		":set printer foo",
+		":set tags foo-bar",
+		":set prompt \"go",
+		":show foo",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, "autoimport", strings.Fields(stdout.String())[0])
+	assert.Contains(t, stdout.String(), "autoimport  = on ")
+	assert.Contains(t, stdout.String(), "printer     = json ")
+	assert.Contains(t, stdout.String(), "timeout     = off ")
+	assert.Contains(t, stdout.String(), "tags        = foo,bar_baz ")
+	assert.Contains(t, stdout.String(), `prompt      = "go> " `)
+	assert.True(t, strings.HasSuffix(stdout.String(), "\ntags = off\n"))
+	assert.Equal(t, "go> ", s.prompt)
+	assert.Equal(t, `set: invalid autoimport: invalid argument: yes
+set: invalid printer: unknown printer: foo
+set: invalid tags: invalid build tag: foo-bar
+set: invalid prompt: invalid syntax
+show: unknown setting: foo
+`, stderr.String())
+}
+
+func TestCompleteSet(t *testing.T) {
+	assert.Equal(t, []string{"timeout "}, completeSet(nil, "ti"))
+	assert.Equal(t, []string{"timeout off", "timeout 10s", "timeout 1m"}, completeSet(nil, "timeout "))
+	assert.Equal(t, []string{"memlimit  1G"}, completeSet(nil, "memlimit  1"))
+	assert.Equal(t, []string{"autoimport on", "autoimport off"}, completeSet(nil, "autoimport "))
+	assert.Equal(t, []string{"printer json"}, completeSet(nil, "printer j"))
+	assert.Empty(t, completeSet(nil, "foo "))
+	assert.Equal(t, []string{"timeout", "tags"}, completeShow(nil, "t"))
+}
+
+func TestSessionEval_MemoryLimit(t *testing.T) {
+	if !limitsSupported {
+		t.Skip("resource limits are not supported")
+	}
+
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	require.NoError(t, s.Eval(":set memlimit 128M"))
+	require.NoError(t, s.Eval("len(make([]byte, 1<<20))"))
+	require.Equal(t, ErrCmdRun, s.Eval("len(make([]byte, 1<<30))"))
+	// the limit is set before the variables are initialized
+	require.NoError(t, s.Eval(":import syscall"))
+	require.NoError(t, s.Eval("var limit = func() uint64 { var r syscall.Rlimit; syscall.Getrlimit(syscall.RLIMIT_DATA, &r); return r.Cur }()"))
+	require.NoError(t, s.Eval(":set memlimit off"))
+	require.NoError(t, s.Eval("len(make([]byte, 1<<30))"))
+
+	assert.Equal(t, "1048576\n0x8000000\n1073741824\n", stdout.String())
+	assert.Regexp(t, "(out of|cannot allocate) memory", stderr.String())
+}
+
+func TestParseSize(t *testing.T) {
+	testCases := []struct {
+		src  string
+		size uint64
+		str  string
+		err  string
+	}{
+		{src: "off", size: 0, str: "off"},
+		{src: "1000", size: 1000, str: "1000"},
+		{src: "2048", size: 2048, str: "2K"},
+		{src: "512M", size: 512 << 20, str: "512M"},
+		{src: "1536MiB", size: 1536 << 20, str: "1536M"},
+		{src: "1gb", size: 1 << 30, str: "1G"},
+		{src: "1X", err: "invalid size: 1X"},
+		{src: "-1", err: "invalid size: -1"},
+		{src: "16777216T", err: "invalid size: 16777216T"},
+	}
+
+	for _, tc := range testCases {
+		size, err := parseSize(tc.src)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err)
+			continue
+		}
+		require.NoError(t, err, tc.src)
+		assert.Equal(t, tc.size, size, tc.src)
+		assert.Equal(t, tc.str, formatSize(size), tc.src)
+	}
+}
@@ -0,0 +1,506 @@
+package gore
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	gotypes "go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// printSource truncates the values printed by the printer function, which is
+// replaced with limitedPrinterTemplate in the built program. The limits are
+// read from GORE_PRINT (elements, depth, string length and output bytes), and
+// the full outputs are written to the file of GORE_FULL. The names of the types
+// in GORE_RENAMED are replaced with the declared ones.
+const printSource = `package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"unsafe"
+)
+
+type __gore_w = io.Writer
+
+// the types declared again are renamed by gore (see unshadow)
+var __gore_shadowed = regexp.MustCompile("__gore_(\\w+)_\\d+\\b")
+
+var (
+	__gore_limits  [4]int
+	__gore_full    *os.File
+	__gore_types   []string
+	__gore_renamed = map[string]bool{}
+)
+
+func init() {
+	for i, v := range strings.Split(os.Getenv("GORE_PRINT"), ",") {
+		if i < len(__gore_limits) {
+			__gore_limits[i], _ = strconv.Atoi(v)
+		}
+	}
+	if name := os.Getenv("GORE_FULL"); name != "" {
+		__gore_full, _ = os.Create(name)
+	}
+	if types := os.Getenv("GORE_TYPES"); types != "" {
+		__gore_types = strings.Split(types, "\n")
+	}
+	for _, name := range strings.Fields(os.Getenv("GORE_RENAMED")) {
+		__gore_renamed[name] = true
+	}
+}
+
+// __gore_unshadow replaces the names of the renamed types in the output with
+// the declared ones.
+func __gore_unshadow(b []byte) []byte {
+	if len(__gore_renamed) == 0 {
+		return b
+	}
+	return __gore_shadowed.ReplaceAllFunc(b, func(name []byte) []byte {
+		if !__gore_renamed[string(name)] {
+			return name
+		}
+		return __gore_shadowed.ReplaceAll(name, []byte("$1"))
+	})
+}
+
+// __gore_type returns the type of the value printed next.
+func __gore_type() string {
+	if len(__gore_types) == 0 {
+		return ""
+	}
+	typ := __gore_types[0]
+	__gore_types = __gore_types[1:]
+	return typ
+}
+
+// __gore_print prints the value with the function writing to the writer.
+func __gore_print(x any, print func(w __gore_w, x any)) {
+	t := __gore_truncater{seen: map[unsafe.Pointer]reflect.Value{}}
+	y := t.truncate(x)
+	var buf bytes.Buffer
+	print(&buf, y)
+	b := __gore_unshadow(buf.Bytes())
+	if __gore_full != nil {
+		if t.truncated() {
+			var full bytes.Buffer
+			print(&full, x)
+			__gore_full.Write(__gore_unshadow(full.Bytes()))
+		} else {
+			__gore_full.Write(b)
+		}
+	}
+	t.write(b, __gore_type())
+}
+
+// __gore_call prints the value with the function writing to the standard
+// output. The function is called only once, so the output is captured and
+// limited only by bytes.
+func __gore_call(x any, print func(x any)) {
+	b := __gore_unshadow(__gore_capture(func() { print(x) }))
+	if __gore_full != nil {
+		__gore_full.Write(b)
+	}
+	var t __gore_truncater
+	t.write(b, __gore_type())
+}
+
+// __gore_capture returns the standard output written by the function.
+func __gore_capture(f func()) (b []byte) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		f()
+		return nil
+	}
+	done := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		r.Close()
+		done <- b
+	}()
+	stdout := os.Stdout
+	os.Stdout = w
+	var returned bool
+	defer func() {
+		os.Stdout = stdout
+		w.Close()
+		b = <-done
+		if !returned {
+			// the function panicked, so show the output before the panic
+			os.Stdout.Write(b)
+		}
+	}()
+	f()
+	returned = true
+	return
+}
+
+// ` + labelerName + ` prints the label of the value printed next.
+func ` + labelerName + `(label string) {
+	os.Stdout.WriteString(label)
+}
+
+type __gore_truncater struct {
+	elems, chars, values, bytes int
+	seen                        map[unsafe.Pointer]reflect.Value
+}
+
+func (t *__gore_truncater) truncate(x any) any {
+	if x == nil {
+		return nil
+	}
+	v := reflect.ValueOf(x)
+	if w, ok := t.value(v, 0); ok {
+		return w.Interface()
+	}
+	return x
+}
+
+func (t *__gore_truncater) truncated() bool {
+	return t.elems > 0 || t.chars > 0 || t.values > 0
+}
+
+// value returns the truncated copy of the value, and whether it is truncated.
+func (t *__gore_truncater) value(v reflect.Value, depth int) (reflect.Value, bool) {
+	maxElems, maxDepth, maxString := __gore_limits[0], __gore_limits[1], __gore_limits[2]
+	if v.CanAddr() {
+		// the unexported fields are copied as well
+		v = reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+	} else if k := v.Kind(); k == reflect.Struct || k == reflect.Array {
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			return v, false
+		}
+		if maxDepth > 0 && depth >= maxDepth {
+			t.values++
+			return reflect.Zero(v.Type()), true
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		if maxString <= 0 || len(s) <= maxString {
+			return v, false
+		}
+		n := maxString
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+		t.chars += len(s) - n
+		return reflect.ValueOf(s[:n]).Convert(v.Type()), true
+
+	case reflect.Pointer:
+		p := v.UnsafePointer()
+		if w, ok := t.seen[p]; ok {
+			return w, w.UnsafePointer() != p
+		}
+		t.seen[p] = v
+		e, ok := t.value(v.Elem(), depth)
+		if !ok {
+			return v, false
+		}
+		w := reflect.New(v.Type().Elem())
+		w.Elem().Set(e)
+		t.seen[p] = w.Convert(v.Type())
+		return t.seen[p], true
+
+	case reflect.Interface:
+		e, ok := t.value(v.Elem(), depth)
+		if !ok {
+			return v, false
+		}
+		w := reflect.New(v.Type()).Elem()
+		w.Set(e)
+		return w, true
+
+	case reflect.Slice:
+		n, changed := v.Len(), false
+		if maxElems > 0 && n > maxElems {
+			t.elems += n - maxElems
+			n, changed = maxElems, true
+		}
+		elems := make([]reflect.Value, n)
+		for i := range elems {
+			var ok bool
+			elems[i], ok = t.value(v.Index(i), depth+1)
+			changed = changed || ok
+		}
+		if !changed {
+			return v, false
+		}
+		w := reflect.MakeSlice(v.Type(), n, n)
+		for i, e := range elems {
+			w.Index(i).Set(e)
+		}
+		return w, true
+
+	case reflect.Array:
+		changed := false
+		w := reflect.New(v.Type()).Elem()
+		w.Set(v)
+		for i := range v.Len() {
+			if e, ok := t.value(v.Index(i), depth+1); ok {
+				w.Index(i).Set(e)
+				changed = true
+			}
+		}
+		return w, changed
+
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return __gore_less(keys[i], keys[j]) })
+		changed := false
+		if maxElems > 0 && len(keys) > maxElems {
+			t.elems += len(keys) - maxElems
+			keys, changed = keys[:maxElems], true
+		}
+		elems := make([]reflect.Value, len(keys))
+		for i, k := range keys {
+			var ok bool
+			elems[i], ok = t.value(v.MapIndex(k), depth+1)
+			changed = changed || ok
+		}
+		if !changed {
+			return v, false
+		}
+		w := reflect.MakeMapWithSize(v.Type(), len(keys))
+		for i, k := range keys {
+			w.SetMapIndex(k, elems[i])
+		}
+		return w, true
+
+	case reflect.Struct:
+		var w reflect.Value
+		for i := range v.NumField() {
+			e, ok := t.value(v.Field(i), depth+1)
+			if !ok {
+				continue
+			}
+			if !w.IsValid() {
+				w = reflect.New(v.Type()).Elem()
+				w.Set(v)
+			}
+			f := w.Field(i)
+			reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem().Set(e)
+		}
+		if w.IsValid() {
+			return w, true
+		}
+	}
+
+	return v, false
+}
+
+func __gore_less(x, y reflect.Value) bool {
+	switch x.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return x.Int() < y.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return x.Uint() < y.Uint()
+	case reflect.Float32, reflect.Float64:
+		return x.Float() < y.Float()
+	case reflect.String:
+		return x.String() < y.String()
+	default:
+		return fmt.Sprint(x) < fmt.Sprint(y)
+	}
+}
+
+// write writes the output limiting the bytes with the type of the value, and
+// reports what is truncated.
+func (t *__gore_truncater) write(b []byte, typ string) {
+	if max := __gore_limits[3]; max > 0 && len(b) > max {
+		n := max
+		for n > 0 && !utf8.RuneStart(b[n]) {
+			n--
+		}
+		// do not cut the escape sequences of colors
+		if i := bytes.LastIndexByte(b[:n], '\x1b'); i >= 0 && bytes.IndexByte(b[i:n], 'm') < 0 {
+			n = i
+		}
+		t.bytes = len(b) - n
+		b = b[:n:n]
+		if bytes.IndexByte(b, '\x1b') >= 0 {
+			b = append(b, "\x1b[0m"...)
+		}
+		b = append(b, '\n')
+	}
+	if typ != "" {
+		if len(b) > 0 {
+			b = append(bytes.TrimSuffix(b, []byte("\n")), " : "+typ+"\n"...)
+		} else {
+			b = []byte(": " + typ + "\n")
+		}
+	}
+	os.Stdout.Write(b)
+
+	var ms []string
+	for _, m := range []struct {
+		n         int
+		one, many string
+	}{
+		{t.elems, "element", "elements"},
+		{t.chars, "byte of strings", "bytes of strings"},
+		{t.values, "nested value", "nested values"},
+		{t.bytes, "byte of output", "bytes of output"},
+	} {
+		if m.n == 1 {
+			ms = append(ms, "1 more "+m.one)
+		} else if m.n > 1 {
+			ms = append(ms, strconv.Itoa(m.n)+" more "+m.many)
+		}
+	}
+	if len(ms) > 0 {
+		fmt.Printf("... %s (:full to show all)\n", strings.Join(ms, ", "))
+	}
+}
+`
+
+// limitedPrinterTemplate is the printer function of the built program, which
+// prints the values through printSource.
+const limitedPrinterTemplate = `package main
+
+func ` + printerName + `(xs ...any) {
+	for _, x := range xs {
+		%s
+	}
+}
+`
+
+// useLimitedPrinter replaces the printer function with the one limiting the
+// outputs, and returns the function to restore it.
+func (s *Session) useLimitedPrinter() func() {
+	var code string
+	if s.printer.wcode != "" {
+		code = fmt.Sprintf("__gore_print(x, func(w __gore_w, x any) {\n%s\n})", s.printer.wcode)
+	} else {
+		code = fmt.Sprintf("__gore_call(x, func(x any) {\n%s\n})", s.printer.code)
+	}
+	f, err := parser.ParseFile(s.fset, "gore_printer.go", fmt.Sprintf(limitedPrinterTemplate, code), parser.Mode(0))
+	if err != nil {
+		debugf("useLimitedPrinter :: err = %s", err)
+		return func() {}
+	}
+
+	for i, decl := range s.file.Decls {
+		if decl, ok := decl.(*ast.FuncDecl); ok && decl.Name.Name == printerName {
+			s.file.Decls[i] = f.Decls[0]
+			return func() { s.file.Decls[i] = decl }
+		}
+	}
+	return func() {}
+}
+
+func (s *Session) fullOutputPath() string {
+	return filepath.Join(s.tempDir, "gore_full.txt")
+}
+
+// printEnv returns the environment variables of the limits for printSource.
+func (s *Session) printEnv() []string {
+	env := []string{
+		fmt.Sprintf("GORE_PRINT=%d,%d,%d,%d", s.maxElems, s.maxDepth, s.maxString, s.maxBytes),
+		"GORE_FULL=" + s.fullOutputPath() + ".new",
+	}
+	if s.showType {
+		env = append(env, "GORE_TYPES="+strings.Join(s.printedTypes(), "\n"))
+	}
+	if names := s.renamedTypes(); len(names) > 0 {
+		env = append(env, "GORE_RENAMED="+strings.Join(names, " "))
+	}
+	return env
+}
+
+// renamedTypes returns the names of the types renamed by redefine.
+func (s *Session) renamedTypes() []string {
+	var names []string
+	ast.Inspect(s.file, func(n ast.Node) bool {
+		if spec, ok := n.(*ast.TypeSpec); ok && shadowedNamePattern.FindString(spec.Name.Name) == spec.Name.Name {
+			names = append(names, spec.Name.Name)
+		}
+		return true
+	})
+	return names
+}
+
+// qualifyByName qualifies the types by the package names as the printers do.
+func qualifyByName(pkg *gotypes.Package) string {
+	return pkg.Name()
+}
+
+// printedTypes returns the types of the values printed for the current input.
+func (s *Session) printedTypes() []string {
+	var types []string
+	for _, stmt := range s.mainBody.List {
+		if s.stmtInputs[stmt] != s.inputCount {
+			continue
+		}
+		for _, expr := range printedExprs(stmt) {
+			switch typ := s.typeInfo.TypeOf(expr).(type) {
+			case nil:
+				types = append(types, "")
+			case *gotypes.Tuple:
+				for v := range typ.Variables() {
+					types = append(types, unshadow(gotypes.TypeString(v.Type(), qualifyByName)))
+				}
+			default:
+				types = append(types, unshadow(gotypes.TypeString(typ, qualifyByName)))
+			}
+		}
+	}
+	return types
+}
+
+// saveFullOutput keeps the full output of the last run for :full command,
+// unless nothing is printed.
+func (s *Session) saveFullOutput() {
+	path := s.fullOutputPath()
+	if fi, err := os.Stat(path + ".new"); err != nil || fi.Size() == 0 {
+		os.Remove(path + ".new")
+		return
+	}
+	if err := os.Rename(path+".new", path); err != nil {
+		debugf("saveFullOutput :: err = %s", err)
+	}
+}
+
+func actionFull(s *Session, _ string) error {
+	f, err := os.Open(s.fullOutputPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.New("no result to show")
+		}
+		return err
+	}
+	defer f.Close()
+
+	if pagerCmd := strings.Fields(s.pager); len(pagerCmd) > 0 {
+		pager := exec.Command(pagerCmd[0], pagerCmd[1:]...)
+		pager.Stdin = f
+		pager.Stdout = s.stdout
+		pager.Stderr = s.stderr
+		return pager.Run()
+	}
+
+	_, err = io.Copy(s.stdout, f)
+	return err
+}
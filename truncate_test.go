@@ -0,0 +1,120 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionEval_Truncate(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		":full",
+		":set maxelems 3",
+		":set maxstring 5",
+		":set maxdepth 2",
+		"[]int{1, 2, 3, 4, 5}",
+		":full",
+		"map[string]int{\"a\": 1, \"b\": 2, \"c\": 3, \"d\": 4}",
+		`"こんにちは"`,
+		"type T struct { s []string; t any }",
+		`T{[]string{"foo", "barbaz"}, []any{[]int{1}}}`,
+		"[3]string{\"a\", \"bcdefgh\"}",
+		":set maxelems off",
+		":set maxbytes 10",
+		"[]int{1, 2, 3, 4, 5}",
+		"var x struct{}",
+		":full",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `[]int{1, 2, 3}
+... 2 more elements (:full to show all)
+[]int{1, 2, 3, 4, 5}
+map[string]int{"a":1, "b":2, "c":3}
+... 1 more element (:full to show all)
+"こ"
+... 12 more bytes of strings (:full to show all)
+main.T{s:[]string{"foo", "barba"}, t:[]interface {}{interface {}(nil)}}
+... 1 more byte of strings, 1 more nested value (:full to show all)
+[3]string{"a", "bcdef", ""}
+... 2 more bytes of strings (:full to show all)
+[]int{1, 2
+... 11 more bytes of output (:full to show all)
+[]int{1, 2, 3, 4, 5}
+`, stdout.String())
+	assert.Equal(t, "full: no result to show\n", stderr.String())
+}
+
+func TestAction_Set_Limits(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		":set maxelems",
+		":set maxdepth",
+		":set maxstring",
+		":set maxelems 10",
+		":set maxelems",
+		":set maxbytes 1M",
+		":set maxbytes",
+		":set maxdepth -1",
+		":set maxstring foo",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, "maxelems = off\nmaxdepth = off\nmaxstring = off\nmaxelems = 10\nmaxbytes = 1M\n", stdout.String())
+	assert.Equal(t, `set: invalid maxdepth: invalid limit: -1
+set: invalid maxstring: invalid limit: foo
+`, stderr.String())
+}
+
+func TestSessionEval_ShowType(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		":set showtype on",
+		"[]int{1, 2}",
+		"type T struct { x int }",
+		"T{1}",
+		`func f() (string, error) { return "", nil }`,
+		"f()",
+		"nil",
+		":set maxelems 1",
+		"[]int{1, 2}",
+		":set showtype off",
+		"3",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `[]int{1, 2} : []int
+main.T{x:1} : main.T
+"" : string
+<nil> : error
+<nil> : untyped nil
+[]int{1} : []int
+... 1 more element (:full to show all)
+3
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
@@ -0,0 +1,132 @@
+package gore
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"slices"
+)
+
+// snapshot is a state of the session code, which is recorded for each input
+// so that the input can be undone.
+type snapshot struct {
+	source   string
+	inputs   []int         // input numbers of the statements in main
+	inputLog []loggedInput // inputs which made the code
+	printer  valuePrinter
+}
+
+func (s *Session) snapshot() (*snapshot, error) {
+	source, err := s.source(false)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make([]int, len(s.mainBody.List))
+	for i := range s.mainBody.List {
+		inputs[i] = s.inputOf(i)
+	}
+
+	return &snapshot{source: source, inputs: inputs, inputLog: slices.Clone(s.inputLog), printer: s.printer}, nil
+}
+
+func (s *Session) restoreSnapshot(snap *snapshot) error {
+	if err := s.restoreSource(snap); err != nil {
+		return err
+	}
+
+	// the saved variables may not be in the code anymore
+	return s.resetState()
+}
+
+// restoreSource restores the code of the snapshot, keeping the variables
+// saved in the persistent mode.
+func (s *Session) restoreSource(snap *snapshot) error {
+	file, err := parser.ParseFile(s.fset, "gore_session.go", snap.source, parser.Mode(0))
+	if err != nil {
+		return err
+	}
+
+	s.file = file
+	s.mainBody = s.mainFunc().Body
+	s.inputLog = snap.inputLog
+	s.printer = snap.printer
+	s.stmtInputs = make(map[ast.Stmt]int, len(s.mainBody.List))
+	for i, stmt := range s.mainBody.List {
+		if i < len(snap.inputs) {
+			s.stmtInputs[stmt] = snap.inputs[i]
+			// the numbers of the later inputs must not conflict (e.g. :clear)
+			s.inputCount = max(s.inputCount, snap.inputs[i])
+		}
+	}
+	return nil
+}
+
+// loggedInput is an input which made the code, with the input number for the
+// result variable (zero for the commands).
+type loggedInput struct {
+	in string
+	n  int
+}
+
+// recordHistory records the input and the state before it to the undo
+// history if the input changed the code.
+func (s *Session) recordHistory(snap *snapshot, in string, n int) {
+	if snap == nil {
+		return
+	}
+
+	source, err := s.source(false)
+	if err != nil || source == snap.source {
+		return
+	}
+
+	s.logInput(in, n)
+	s.pushHistory(snap)
+}
+
+// logInput logs the input for the session files.
+func (s *Session) logInput(in string, n int) {
+	s.inputLog = append(s.inputLog, loggedInput{in: in, n: n})
+}
+
+func (s *Session) pushHistory(snap *snapshot) {
+	s.undoStack = append(s.undoStack, snap)
+	s.redoStack = nil
+}
+
+func (s *Session) undo(n int) error {
+	return s.moveHistory(n, &s.undoStack, &s.redoStack, "undo")
+}
+
+func (s *Session) redo(n int) error {
+	return s.moveHistory(n, &s.redoStack, &s.undoStack, "redo")
+}
+
+// moveHistory restores the n-th state in the stack from, pushing the states
+// in between to the stack to.
+func (s *Session) moveHistory(n int, from, to *[]*snapshot, name string) error {
+	if len(*from) == 0 {
+		return fmt.Errorf("nothing to %s", name)
+	}
+	if n > len(*from) {
+		return fmt.Errorf("cannot %s %d inputs, only %d available", name, n, len(*from))
+	}
+
+	for range n {
+		current, err := s.snapshot()
+		if err != nil {
+			return err
+		}
+
+		snap := (*from)[len(*from)-1]
+		if err := s.restoreSnapshot(snap); err != nil {
+			return err
+		}
+
+		*from = (*from)[:len(*from)-1]
+		*to = append(*to, current)
+	}
+
+	return nil
+}
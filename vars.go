@@ -0,0 +1,260 @@
+package gore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const labelerName = "__gore_label"
+
+// sessionObjects returns the objects declared in the session, in the order of
+// the declarations. The objects declared in the included files are excluded.
+func (s *Session) sessionObjects(f func(types.Object) bool) []types.Object {
+	pkg := s.checkSession()
+	if pkg == nil {
+		return nil
+	}
+
+	visible := func(obj types.Object) bool {
+		name := obj.Name()
+		return name != "_" && name != "main" && !strings.HasPrefix(name, "__gore_") &&
+			!resultNamePattern.MatchString(name)
+	}
+	include := func(obj types.Object) bool {
+		return visible(obj) && f(obj)
+	}
+
+	var objs []types.Object
+	for _, name := range pkg.Scope().Names() {
+		obj := pkg.Scope().Lookup(name)
+		if !s.inSession(obj) || !visible(obj) {
+			continue
+		}
+		if f(obj) {
+			objs = append(objs, obj)
+		}
+		if obj, ok := obj.(*types.TypeName); ok && !obj.IsAlias() {
+			if named, ok := obj.Type().(*types.Named); ok {
+				for m := range named.Methods() {
+					if f(m) {
+						objs = append(objs, m)
+					}
+				}
+			}
+		}
+	}
+	slices.SortFunc(objs, func(x, y types.Object) int { return int(x.Pos() - y.Pos()) })
+
+	// the positions in main are normalized, so follow the identifiers
+	scope := s.typeInfo.Scopes[s.mainFunc().Type]
+	ast.Inspect(s.mainBody, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			if obj := s.typeInfo.Defs[ident]; obj != nil && obj.Parent() == scope && include(obj) {
+				objs = append(objs, obj)
+			}
+		}
+		return true
+	})
+	return objs
+}
+
+// checkSession type checks the session ignoring the errors, like the unused
+// variables which are not fixed yet.
+func (s *Session) checkSession() *types.Package {
+	s.typeInfo = types.Info{
+		Types:  make(map[ast.Expr]types.TypeAndValue),
+		Uses:   make(map[*ast.Ident]types.Object),
+		Defs:   make(map[*ast.Ident]types.Object),
+		Scopes: make(map[ast.Node]*types.Scope),
+	}
+	conf := *s.types
+	conf.Error = func(error) {}
+	pkg, _ := conf.Check("_tmp", s.fset, append(s.extraFiles, s.file), &s.typeInfo)
+	return pkg
+}
+
+// inSession reports whether the package level object is declared in the
+// session, not in the included files.
+func (s *Session) inSession(obj types.Object) bool {
+	file := s.fset.File(obj.Pos())
+	for _, f := range s.extraFiles {
+		if s.fset.File(f.Pos()) == file {
+			return false
+		}
+	}
+	return true
+}
+
+// objectName returns the name of the object, qualified by the receiver type
+// for methods (e.g. T.String).
+func objectName(obj types.Object) string {
+	if fn, ok := obj.(*types.Func); ok {
+		if recv := fn.Signature().Recv(); recv != nil {
+			typ := recv.Type()
+			if ptr, ok := typ.(*types.Pointer); ok {
+				typ = ptr.Elem()
+			}
+			if named, ok := typ.(*types.Named); ok {
+				return named.Obj().Name() + "." + fn.Name()
+			}
+		}
+	}
+	return obj.Name()
+}
+
+// formatObjects formats the names and the types of the objects aligned.
+func formatObjects(objs []types.Object, typ func(types.Object) types.Type) []string {
+	var width int
+	for _, obj := range objs {
+		width = max(width, len(objectName(obj)))
+	}
+	lines := make([]string, len(objs))
+	for i, obj := range objs {
+		lines[i] = fmt.Sprintf("%-*s %s", width, objectName(obj), unshadow(types.TypeString(typ(obj), qualifySession)))
+	}
+	return lines
+}
+
+// qualifySession qualifies the types by the package names, except for the
+// ones declared in the session.
+func qualifySession(pkg *types.Package) string {
+	if pkg.Path() == "_tmp" {
+		return ""
+	}
+	return pkg.Name()
+}
+
+// qualifyByPath qualifies the types by the package paths, except for the ones
+// declared in the session.
+func qualifyByPath(pkg *types.Package) string {
+	if pkg.Path() == "_tmp" {
+		return ""
+	}
+	return pkg.Path()
+}
+
+// typeString formats the type as :type prints, with the names of the types
+// redefined in the session as they are declared.
+func typeString(typ types.Type) string {
+	return unshadow(types.TypeString(typ, qualifyByPath))
+}
+
+func actionVars(s *Session, arg string) error {
+	var withValues bool
+	switch arg {
+	case "":
+	case "-v":
+		withValues = true
+	default:
+		return fmt.Errorf("invalid argument: %s", arg)
+	}
+
+	s.clearQuickFix()
+
+	s.storeCode()
+	defer s.restoreCode()
+
+	objs := s.sessionObjects(func(obj types.Object) bool {
+		_, ok := obj.(*types.Var)
+		return ok
+	})
+	lines := formatObjects(objs, types.Object.Type)
+	if !withValues || len(objs) == 0 {
+		for _, line := range lines {
+			fmt.Fprintln(s.stdout, line)
+		}
+		return nil
+	}
+	return s.printVars(objs, lines)
+}
+
+// printVars runs the session to print the values of the variables, with the
+// labels printed by the session program so that the outputs are in order.
+// The previous inputs may have side effects, so the values are printed only
+// when they are restored in the persistent mode.
+func (s *Session) printVars(objs []types.Object, labels []string) error {
+	if !s.persist {
+		return errors.New("the values are printed only in the persistent mode (gore -persist)")
+	}
+	s.restoreOnly = true
+	s.inputCount++
+	defer func() {
+		s.restoreOnly = false
+		s.inputCount--
+	}()
+
+	labelOf := make(map[string]string, len(objs))
+	for i, obj := range objs {
+		labelOf[obj.Name()] = labels[i] + " = "
+		s.mainBody.List = append(s.mainBody.List, buildPrintStmt([]ast.Expr{ast.NewIdent(obj.Name())}))
+	}
+	s.doQuickFix()
+
+	// the labeler is in the print source, which is not type checked
+	for i := 0; i < len(s.mainBody.List); i++ {
+		exprs := printedExprs(s.mainBody.List[i])
+		if len(exprs) != 1 {
+			continue
+		}
+		ident, ok := exprs[0].(*ast.Ident)
+		if !ok || labelOf[ident.Name] == "" {
+			continue
+		}
+		label := &ast.ExprStmt{X: &ast.CallExpr{
+			Fun:  ast.NewIdent(labelerName),
+			Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(labelOf[ident.Name])}},
+		}}
+		s.stmtInputs[label] = s.inputCount
+		s.mainBody.List = slices.Insert(s.mainBody.List, i, ast.Stmt(label))
+		delete(labelOf, ident.Name)
+		i++
+	}
+
+	if err := s.run(context.Background()); err != nil {
+		if errors.Is(err, errCannotRestore) {
+			return err
+		}
+		return errors.New("cannot print the values")
+	}
+	return nil
+}
+
+func actionFuncs(s *Session, _ string) error {
+	s.clearQuickFix()
+
+	s.storeCode()
+	defer s.restoreCode()
+
+	objs := s.sessionObjects(func(obj types.Object) bool {
+		_, ok := obj.(*types.Func)
+		return ok
+	})
+	for _, line := range formatObjects(objs, types.Object.Type) {
+		fmt.Fprintln(s.stdout, line)
+	}
+	return nil
+}
+
+func actionTypes(s *Session, _ string) error {
+	s.clearQuickFix()
+
+	s.storeCode()
+	defer s.restoreCode()
+
+	objs := s.sessionObjects(func(obj types.Object) bool {
+		_, ok := obj.(*types.TypeName)
+		return ok
+	})
+	underlying := func(obj types.Object) types.Type { return obj.Type().Underlying() }
+	for _, line := range formatObjects(objs, underlying) {
+		fmt.Fprintln(s.stdout, line)
+	}
+	return nil
+}
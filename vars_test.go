@@ -0,0 +1,96 @@
+package gore
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_Vars(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		":vars",
+		":import fmt",
+		"x := 1",
+		"type T struct { a int }",
+		`name, t := "gore", T{2}`,
+		"func f(n int) string { return \"\" }",
+		`fmt.Println("side effect")`,
+		":vars",
+		":vars -v",
+		":funcs",
+		":types",
+		":vars foo",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `1
+"gore"
+main.T{a:2}
+side effect
+12
+<nil>
+x    int
+name string
+t    T
+f func(n int) string
+T struct{a int}
+`, stdout.String())
+	assert.Equal(t, `vars: the values are printed only in the persistent mode (gore -persist)
+vars: invalid argument: foo
+`, stderr.String())
+}
+
+func TestAction_VarsPersist(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+	s.persist = true
+
+	codes := []string{
+		":import fmt",
+		"x := 1",
+		"type T struct { A int }",
+		`name, t := "gore", T{2}`,
+		`fmt.Println("side effect")`,
+		"x++",
+		":vars -v",
+		"for i := range 3 { x += i }",
+		":vars -v",
+		"x",
+		"p := &x",
+		":vars -v",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, `1
+"gore"
+main.T{A:2}
+side effect
+12
+<nil>
+x    int = 2
+name string = "gore"
+t    T = main.T{A:2}
+x    int = 5
+name string = "gore"
+t    T = main.T{A:2}
+5
+(*int)(...)
+`, regexp.MustCompile(`0x[0-9a-f]+`).ReplaceAllString(stdout.String(), "..."))
+	assert.Equal(t, "vars: cannot restore the variables without running the previous inputs\n", stderr.String())
+}
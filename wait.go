@@ -0,0 +1,69 @@
+package gore
+
+import (
+	"go/ast"
+	"slices"
+	"time"
+)
+
+const waiterName = "__gore_wait"
+
+// waitSource waits for the goroutines started by the program after the
+// statements of main, at most for the duration of GORE_WAIT, and reports the
+// goroutines still running with their stack traces.
+const waitSource = `package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+)
+
+var __gore_goroutines = runtime.NumGoroutine()
+
+func ` + waiterName + `() {
+	wait, _ := time.ParseDuration(os.Getenv("GORE_WAIT"))
+	deadline := time.Now().Add(wait)
+	for runtime.NumGoroutine() > __gore_goroutines && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	n := runtime.NumGoroutine() - __gore_goroutines
+	if n <= 0 {
+		return
+	}
+
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	unit := "goroutines"
+	if n == 1 {
+		unit = "goroutine"
+	}
+	fmt.Fprintf(os.Stderr, "wait: %d %s still running after %s\n", n, unit, wait)
+	// the first one is the current goroutine
+	if i := bytes.Index(buf, []byte("\n\n")); i >= 0 {
+		os.Stderr.Write(buf[i+1:])
+	}
+}
+`
+
+// useWaiter appends the call of the waiter to main, and returns the function
+// to remove it.
+func (s *Session) useWaiter() func() {
+	if s.wait <= 0 {
+		return func() {}
+	}
+	stmt := &ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent(waiterName)}}
+	// the statement is run again in the persistent mode
+	s.appendStatements(stmt)
+	return func() {
+		s.mainBody.List = slices.DeleteFunc(s.mainBody.List, func(st ast.Stmt) bool { return st == stmt })
+		delete(s.stmtInputs, stmt)
+	}
+}
+
+// waitEnv returns the environment variable of the duration for waitSource.
+func waitEnv(wait time.Duration) []string {
+	return []string{"GORE_WAIT=" + wait.String()}
+}
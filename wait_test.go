@@ -0,0 +1,38 @@
+package gore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionEval_Wait(t *testing.T) {
+	var stdout, stderr strings.Builder
+	s, err := NewSession(&stdout, &stderr)
+	t.Cleanup(func() { s.Clear() })
+	require.NoError(t, err)
+
+	codes := []string{
+		":import fmt sync time",
+		":set wait 1s",
+		`go func() { time.Sleep(10 * time.Millisecond); fmt.Println("b") }()`,
+		"var wg sync.WaitGroup",
+		"var ch chan int",
+		"wg.Add(1)",
+		"go func() { defer wg.Done(); ch <- 1 }()",
+	}
+
+	for _, code := range codes {
+		_ = s.Eval(code)
+	}
+
+	assert.Equal(t, "b\nb\nb\nb\nb\n", stdout.String())
+	assert.Regexp(t, `^wait: 1 goroutine still running after 1s
+
+goroutine \d+ \[chan send \(nil chan\)\]:
+    main\.func2 at input 5
+    created by main in goroutine 1 at input 5
+$`, stderr.String())
+}